@@ -1,17 +1,23 @@
 package api
 
 import (
-	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
 
+	"github.com/ONSdigital/dp-census-dataset-search-api/config"
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/metrics"
 	"github.com/ONSdigital/dp-census-dataset-search-api/models"
-	"github.com/ONSdigital/go-ns/server"
-	"github.com/ONSdigital/log.go/log"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-var httpServer *server.Server
+// ErrMissingElasticsearch is returned by New when no elasticsearch client is set
+var ErrMissingElasticsearch = errors.New("missing elasticsearch client, set one with WithElasticsearch")
 
-// SearchAPI manages searches across indices
+// SearchAPI manages searches across indices, it is an http.Handler so it can be
+// served directly or mounted within another router
 type SearchAPI struct {
 	datasetIndex      string
 	defaultMaxResults int
@@ -19,65 +25,110 @@ type SearchAPI struct {
 	elasticsearch     Elasticsearcher
 	router            *mux.Router
 	taxonomy          models.Taxonomy
+	mutex             sync.RWMutex
+
+	dimensionsLoadedAt time.Time
+	taxonomyLoadedAt   time.Time
+	health             models.Health
+	healthDone         chan struct{}
+	healthWG           sync.WaitGroup
+	shuttingDown       bool
+
+	metrics           *metrics.API
+	metricsRegisterer prometheus.Registerer
 }
 
-// CreateAndInitialiseSearchAPI manages all the routes configured to API
-func CreateAndInitialiseSearchAPI(ctx context.Context, bindAddr string, esAPI Elasticsearcher, defaultMaxResults int, datasetIndex string, dimensions models.DimensionsDoc, taxonomy models.Taxonomy, errorChan chan error) {
-
-	router := mux.NewRouter()
-	routes(ctx,
-		router,
-		esAPI,
-		defaultMaxResults,
-		datasetIndex,
-		dimensions,
-		taxonomy,
-	)
-
-	httpServer = server.New(bindAddr, router)
-
-	// Disable this here to allow service to manage graceful shutdown of the entire app.
-	httpServer.HandleOSSignals = false
-
-	go func() {
-		log.Event(ctx, "Starting api...", log.INFO)
-		if err := httpServer.ListenAndServe(); err != nil {
-			log.Event(ctx, "api http server returned error", log.ERROR, log.Error(err))
-			errorChan <- err
+// New creates a SearchAPI with all routes configured, the caller is responsible
+// for serving it and managing the lifecycle of the http server. Settings without
+// an option take the defaults of the service configuration
+func New(opts ...Option) (*SearchAPI, error) {
+	loadedAt := time.Now().UTC()
+
+	api := &SearchAPI{
+		datasetIndex:       config.DefaultDatasetIndex,
+		defaultMaxResults:  config.DefaultMaxSearchResultsOffset,
+		router:             mux.NewRouter(),
+		dimensionsLoadedAt: loadedAt,
+		taxonomyLoadedAt:   loadedAt,
+		health: models.Health{
+			Status: models.HealthCritical,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(api)
+	}
+
+	if api.elasticsearch == nil {
+		return nil, ErrMissingElasticsearch
+	}
+
+	if api.metricsRegisterer != nil {
+		m, err := metrics.NewAPI(api.metricsRegisterer)
+		if err != nil {
+			return nil, err
 		}
-	}()
+		api.metrics = m
+	}
+
+	api.routes()
+
+	return api, nil
 }
 
-func routes(ctx context.Context,
-	router *mux.Router,
-	elasticsearch Elasticsearcher,
-	defaultMaxResults int,
-	datasetIndex string,
-	dimensions models.DimensionsDoc,
-	taxonomy models.Taxonomy) *SearchAPI {
-
-	api := SearchAPI{
-		datasetIndex:      datasetIndex,
-		defaultMaxResults: defaultMaxResults,
-		dimensions:        dimensions,
-		elasticsearch:     elasticsearch,
-		router:            router,
-		taxonomy:          taxonomy,
-	}
+// ServeHTTP serves requests to the search api routes
+func (api *SearchAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.router.ServeHTTP(w, r)
+}
+
+func (api *SearchAPI) routes() {
+	api.metrics.SetTaxonomyTopics(countTopics(api.taxonomy.Topics))
+	api.metrics.SetDimensions(len(api.dimensions.Dimensions))
+
+	api.router.Use(api.metrics.Middleware)
+	api.router.HandleFunc("/health", api.getHealth).Methods("GET", "OPTIONS")
 
 	api.router.HandleFunc("/datasets", api.getDatasets).Methods("GET", "OPTIONS")
 	api.router.HandleFunc("/dimensions", api.getDimensions).Methods("GET", "OPTIONS")
+	api.router.HandleFunc("/dimensions/{name}/datasets", api.getDimensionDatasets).Methods("GET", "OPTIONS")
 	api.router.HandleFunc("/taxonomy", api.getTaxonomy).Methods("GET", "OPTIONS")
 	api.router.HandleFunc("/taxonomy/{topic}", api.getTopic).Methods("GET", "OPTIONS")
+}
 
-	return &api
+// SetTaxonomy replaces the taxonomy used to serve and validate requests
+func (api *SearchAPI) SetTaxonomy(taxonomy models.Taxonomy) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.taxonomy = taxonomy
+	api.taxonomyLoadedAt = time.Now().UTC()
+	api.metrics.SetTaxonomyTopics(countTopics(taxonomy.Topics))
 }
 
-// Close represents the graceful shutting down of the http server
-func Close(ctx context.Context) error {
-	if err := httpServer.Shutdown(ctx); err != nil {
-		return err
-	}
-	log.Event(ctx, "graceful shutdown of http server complete", log.INFO)
-	return nil
+// SetDimensions replaces the list of dimensions used to serve and validate requests
+func (api *SearchAPI) SetDimensions(dimensions models.DimensionsDoc) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.dimensions = dimensions
+	api.dimensionsLoadedAt = time.Now().UTC()
+	api.metrics.SetDimensions(len(dimensions.Dimensions))
+}
+
+// SetShuttingDown fails the health endpoint so that no new requests are routed
+// to the service while in-flight requests are drained
+func (api *SearchAPI) SetShuttingDown() {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.shuttingDown = true
+}
+
+func (api *SearchAPI) currentTaxonomy() models.Taxonomy {
+	api.mutex.RLock()
+	defer api.mutex.RUnlock()
+	return api.taxonomy
+}
+
+func (api *SearchAPI) currentDimensions() models.DimensionsDoc {
+	api.mutex.RLock()
+	defer api.mutex.RUnlock()
+	return api.dimensions
 }
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/config"
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New(WithDatasetIndex("datasets")); err != ErrMissingElasticsearch {
+		t.Errorf("err = %v, want %v", err, ErrMissingElasticsearch)
+	}
+
+	api := newTestAPI(t, &elasticsearchStub{})
+	if api.datasetIndex != config.DefaultDatasetIndex || api.defaultMaxResults != config.DefaultMaxSearchResultsOffset {
+		t.Errorf("dataset index, max offset = %s, %d, want the configuration defaults", api.datasetIndex, api.defaultMaxResults)
+	}
+
+	// registering the metrics of two apis with one registerer fails
+	reg := prometheus.NewRegistry()
+	newTestAPI(t, &elasticsearchStub{}, WithMetrics(reg))
+	if _, err := New(WithElasticsearch(&elasticsearchStub{}), WithMetrics(reg)); err == nil {
+		t.Error("expected an error registering the metrics twice")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	stub := &elasticsearchStub{}
+	api := newTestAPI(t, stub, WithDatasetIndex("datasets-alias"), WithMaxOffset(20))
+
+	tests := []struct {
+		target   string
+		wantCode int
+	}{
+		{target: "/datasets?q=census&offset=10", wantCode: http.StatusOK},
+		{target: "/datasets?q=census&offset=20", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+		if w.Code != tt.wantCode {
+			t.Errorf("%s code = %d, want %d", tt.target, w.Code, tt.wantCode)
+		}
+	}
+
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	if len(stub.indices) != 1 || stub.indices[0] != "datasets-alias" {
+		t.Errorf("indices searched = %v, want [datasets-alias]", stub.indices)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		wantCode int
+	}{
+		{name: "datasets", method: http.MethodGet, target: "/datasets?q=census", wantCode: http.StatusOK},
+		{name: "dimensions", method: http.MethodGet, target: "/dimensions", wantCode: http.StatusOK},
+		{name: "dimension datasets", method: http.MethodGet, target: "/dimensions/sex/datasets", wantCode: http.StatusOK},
+		{name: "taxonomy", method: http.MethodGet, target: "/taxonomy", wantCode: http.StatusOK},
+		{name: "topic", method: http.MethodGet, target: "/taxonomy/economy", wantCode: http.StatusOK},
+		{name: "preflight", method: http.MethodOptions, target: "/taxonomy", wantCode: http.StatusNoContent},
+		{name: "unknown route", method: http.MethodGet, target: "/unknown", wantCode: http.StatusNotFound},
+		{name: "unsupported method", method: http.MethodPost, target: "/datasets", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t, &elasticsearchStub{})
+
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestSetTaxonomyAndDimensions(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	api := newTestAPI(t, &elasticsearchStub{}, WithMetrics(reg))
+
+	api.SetDimensions(models.DimensionsDoc{Dimensions: []models.DimensionObject{{Label: "Age", Name: "age"}}, TotalCount: 1})
+	api.SetTaxonomy(models.Taxonomy{Topics: []models.Topic{{Title: "Health", FormattedTitle: "health"}}})
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dimensions/age/datasets", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("new dimension code = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w = httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/taxonomy/economy", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("removed topic code = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	metrics := `
+# HELP dataset_search_dimensions The number of loaded dimensions.
+# TYPE dataset_search_dimensions gauge
+dataset_search_dimensions 1
+# HELP dataset_search_taxonomy_topics The number of topics across all levels of the loaded taxonomy.
+# TYPE dataset_search_taxonomy_topics gauge
+dataset_search_taxonomy_topics 1
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(metrics), "dataset_search_dimensions", "dataset_search_taxonomy_topics")
+	if err != nil {
+		t.Error(err)
+	}
+}
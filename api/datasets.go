@@ -2,6 +2,8 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -29,8 +31,6 @@ func (api *SearchAPI) getDatasets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var err error
-
 	q := r.FormValue("q")
 	requestedLimit := r.FormValue("limit")
 	requestedOffset := r.FormValue("offset")
@@ -56,34 +56,9 @@ func (api *SearchAPI) getDatasets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	limit := defaultLimit
-	if requestedLimit != "" {
-		limit, err = strconv.Atoi(requestedLimit)
-		if err != nil {
-			log.Event(ctx, "getDatasets endpoint: request limit parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
-			return
-		}
-	}
-
-	offset := defaultOffset
-	if requestedOffset != "" {
-		offset, err = strconv.Atoi(requestedOffset)
-		if err != nil {
-			log.Event(ctx, "getDatasets endpoint: request offset parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
-			return
-		}
-	}
-
-	page := &models.PageVariables{
-		DefaultMaxResults: api.defaultMaxResults,
-		Limit:             limit,
-		Offset:            offset,
-	}
-
-	if err = page.Validate(); err != nil {
-		log.Event(ctx, "getDatasets endpoint: validate pagination", log.ERROR, log.Error(err), logData)
+	page, err := api.parsePage(r)
+	if err != nil {
+		log.Event(ctx, "getDatasets endpoint: request pagination parameters error", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, err)
 		return
 	}
@@ -98,7 +73,7 @@ func (api *SearchAPI) getDatasets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	topicFilters, err := models.ValidateTopics(topics)
+	topicFilters, err := models.ValidateTopics(topics, api.currentTaxonomy())
 	if err != nil {
 		log.Event(ctx, "getDatasets endpoint: validate filter by topics", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, err)
@@ -108,9 +83,9 @@ func (api *SearchAPI) getDatasets(w http.ResponseWriter, r *http.Request) {
 	log.Event(ctx, "getDatasets endpoint: just before querying search index", log.INFO, logData)
 
 	// build dataset search query
-	query := buildSearchQuery(term, dimensionFilters, topicFilters, limit, offset)
+	query := buildSearchQuery(term, dimensionFilters, topicFilters, page.Limit, page.Offset)
 
-	response, status, err := api.elasticsearch.QueryDatasetSearch(ctx, api.datasetIndex, query, limit, offset)
+	response, status, err := api.elasticsearch.QueryDatasetSearch(ctx, api.datasetIndex, query, page.Limit, page.Offset)
 	if err != nil {
 		logData["elasticsearch_status"] = status
 		log.Event(ctx, "getDatasets endpoint: failed to get search results", log.ERROR, log.Error(err), logData)
@@ -118,23 +93,12 @@ func (api *SearchAPI) getDatasets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	searchResults := &models.SearchResults{
-		Limit:      page.Limit,
-		Offset:     page.Offset,
-		TotalCount: response.Hits.Total,
-		Items:      []models.SearchResult{},
-	}
-
-	for _, result := range response.Hits.HitList {
+	searchResults := newSearchResults(page, response)
 
-		doc := result.Source
-		doc.Matches = result.Matches
-
-		searchResults.Items = append(searchResults.Items, doc)
+	if searchResults.TotalCount == 0 {
+		api.metrics.IncZeroResultSearches()
 	}
 
-	searchResults.Count = len(searchResults.Items)
-
 	b, err := json.Marshal(searchResults)
 	if err != nil {
 		log.Event(ctx, "getDatasets endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
@@ -159,8 +123,12 @@ func setAccessControl(w http.ResponseWriter, method string) {
 }
 
 func setErrorCode(w http.ResponseWriter, err error) {
+	var unavailable *errs.UnavailableError
 
 	switch {
+	case errors.As(err, &unavailable):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(unavailable.RetryAfter.Seconds()))))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
 	case errs.NotFoundMap[err]:
 		http.Error(w, err.Error(), http.StatusNotFound)
 	case errs.BadRequestMap[err]:
@@ -181,26 +149,20 @@ func buildSearchQuery(term string, dimensionFilters []models.Filter, topicFilter
 	highlight["alias"] = object
 	highlight["description"] = object
 	highlight["title"] = object
-	highlight["topic1"] = object
-	highlight["topic2"] = object
-	highlight["topic3"] = object
+	highlight["topics"] = object
 	highlight["dimensions.label"] = object
 	highlight["dimensions.name"] = object
 
 	alias := make(map[string]string)
 	description := make(map[string]string)
 	title := make(map[string]string)
-	topic1 := make(map[string]string)
-	topic2 := make(map[string]string)
-	topic3 := make(map[string]string)
+	topics := make(map[string]string)
 	dimensionLabels := make(map[string]string)
 	dimensionNames := make(map[string]string)
 	alias["alias"] = term
 	description["description"] = term
 	title["title"] = term
-	topic1["topic1"] = term
-	topic2["topic2"] = term
-	topic3["topic3"] = term
+	topics["topics"] = term
 	dimensionLabels["dimensions.label"] = term
 	dimensionNames["dimensions.name"] = term
 
@@ -216,16 +178,8 @@ func buildSearchQuery(term string, dimensionFilters []models.Filter, topicFilter
 		Match: title,
 	}
 
-	topic1Match := models.Match{
-		Match: topic1,
-	}
-
-	topic2Match := models.Match{
-		Match: topic2,
-	}
-
-	topic3Match := models.Match{
-		Match: topic3,
+	topicsMatch := models.Match{
+		Match: topics,
 	}
 
 	scores := models.Scores{
@@ -251,9 +205,7 @@ func buildSearchQuery(term string, dimensionFilters []models.Filter, topicFilter
 					aliasMatch,
 					descriptionMatch,
 					titleMatch,
-					topic1Match,
-					topic2Match,
-					topic3Match,
+					topicsMatch,
 					{
 						Nested: &models.Nested{
 							Path: "dimensions",
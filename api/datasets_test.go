@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetDatasetsTopics(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantFilter string
+	}{
+		{
+			name:       "topics at different levels must all match",
+			target:     "/datasets?q=prices&topics=economy,consumerprices",
+			wantStatus: http.StatusOK,
+			wantFilter: `"filter":[{"terms":{"topic_path.hierarchy":["economy"]}},{"terms":{"topic_path.hierarchy":["economy/inflationandpriceindices/consumerprices"]}}]`,
+		},
+		{
+			name:       "topics at the same level can match any",
+			target:     "/datasets?q=prices&topics=economy,people",
+			wantStatus: http.StatusOK,
+			wantFilter: `"filter":[{"terms":{"topic_path.hierarchy":["economy","people"]}}]`,
+		},
+		{
+			name:       "unknown topic",
+			target:     "/datasets?q=prices&topics=unknown",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &elasticsearchStub{}
+
+			w := httptest.NewRecorder()
+			newTestAPI(t, stub).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if query := stub.lastQuery(t); !strings.Contains(query, tt.wantFilter) {
+				t.Errorf("query %s, want filter %s", query, tt.wantFilter)
+			}
+		})
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"net/http"
 
 	errs "github.com/ONSdigital/dp-census-dataset-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
 	"github.com/ONSdigital/log.go/log"
+	"github.com/gorilla/mux"
 )
 
 func (api *SearchAPI) getDimensions(w http.ResponseWriter, r *http.Request) {
@@ -19,7 +21,7 @@ func (api *SearchAPI) getDimensions(w http.ResponseWriter, r *http.Request) {
 
 	log.Event(ctx, "getDimensions endpoint: incoming request", log.INFO)
 
-	b, err := json.Marshal(api.dimensions)
+	b, err := json.Marshal(api.currentDimensions())
 	if err != nil {
 		log.Event(ctx, "getDimensions endpoint: failed to marshal dimensions resource into bytes", log.ERROR, log.Error(err))
 		setErrorCode(w, errs.ErrInternalServer)
@@ -33,3 +35,109 @@ func (api *SearchAPI) getDimensions(w http.ResponseWriter, r *http.Request) {
 
 	log.Event(ctx, "getDimensions endpoint: successfully searched index", log.INFO)
 }
+
+func (api *SearchAPI) getDimensionDatasets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	setAccessControl(w, http.MethodGet)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+	requestedLimit := r.FormValue("limit")
+	requestedOffset := r.FormValue("offset")
+
+	logData := log.Data{
+		"dimension":        name,
+		"requested_limit":  requestedLimit,
+		"requested_offset": requestedOffset,
+	}
+
+	log.Event(ctx, "getDimensionDatasets endpoint: incoming request", log.INFO, logData)
+
+	if !api.hasDimension(name) {
+		log.Event(ctx, "getDimensionDatasets endpoint: failed to find dimension", log.ERROR, log.Error(errs.ErrDimensionNotFound), logData)
+		setErrorCode(w, errs.ErrDimensionNotFound)
+		return
+	}
+
+	page, err := api.parsePage(r)
+	if err != nil {
+		log.Event(ctx, "getDimensionDatasets endpoint: request pagination parameters error", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	logData["limit"] = page.Limit
+	logData["offset"] = page.Offset
+
+	dimensionFilters, err := models.ValidateDimensions(name)
+	if err != nil {
+		log.Event(ctx, "getDimensionDatasets endpoint: validate filter by dimension", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	query := buildFilterQuery(dimensionFilters, page.Limit, page.Offset)
+
+	response, status, err := api.elasticsearch.QueryDatasetSearch(ctx, api.datasetIndex, query, page.Limit, page.Offset)
+	if err != nil {
+		logData["elasticsearch_status"] = status
+		log.Event(ctx, "getDimensionDatasets endpoint: failed to get search results", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	searchResults := newSearchResults(page, response)
+
+	b, err := json.Marshal(searchResults)
+	if err != nil {
+		log.Event(ctx, "getDimensionDatasets endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, errs.ErrInternalServer)
+		return
+	}
+
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, "getDimensionDatasets endpoint: error writing response", log.ERROR, log.Error(err), logData)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+
+	log.Event(ctx, "getDimensionDatasets endpoint: successfully searched index", log.INFO, logData)
+}
+
+// hasDimension checks the dimension name exists in the loaded list of dimensions
+func (api *SearchAPI) hasDimension(name string) bool {
+	for _, dimension := range api.currentDimensions().Dimensions {
+		if dimension.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildFilterQuery creates a query that only filters documents, there is no
+// search term so all matching documents are scored equally
+func buildFilterQuery(filters []models.Filter, limit, offset int) interface{} {
+	return &models.Body{
+		From: offset,
+		Size: limit,
+		Query: models.Query{
+			Bool: &models.Bool{
+				Filter: filters,
+			},
+		},
+		Sort: []models.Scores{
+			{
+				Score: models.Score{
+					Order: "desc",
+				},
+			},
+		},
+		TotalHits: true,
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+func TestGetDimensionDatasets(t *testing.T) {
+	response := &models.SearchResponse{
+		Hits: models.Hits{
+			Total: 3,
+			HitList: []models.HitList{
+				{Source: models.SearchResult{Alias: "cpih01", Title: "CPIH"}},
+				{Source: models.SearchResult{Alias: "mid-year-pop-est", Title: "Population"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantLimit  int
+		wantOffset int
+		wantCount  int
+	}{
+		{
+			name:       "datasets for a dimension",
+			target:     "/dimensions/geography/datasets",
+			wantStatus: http.StatusOK,
+			wantLimit:  defaultLimit,
+			wantCount:  2,
+		},
+		{
+			name:       "limit and offset are passed on",
+			target:     "/dimensions/sex/datasets?limit=2&offset=4",
+			wantStatus: http.StatusOK,
+			wantLimit:  2,
+			wantOffset: 4,
+			wantCount:  2,
+		},
+		{
+			name:       "the limit is reduced to the maximum offset",
+			target:     "/dimensions/sex/datasets?limit=50&offset=990",
+			wantStatus: http.StatusOK,
+			wantLimit:  10,
+			wantOffset: 990,
+			wantCount:  2,
+		},
+		{
+			name:       "unknown dimension",
+			target:     "/dimensions/unknown/datasets",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid limit",
+			target:     "/dimensions/geography/datasets?limit=ten",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "offset beyond the maximum",
+			target:     "/dimensions/geography/datasets?offset=1000",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &elasticsearchStub{response: response}
+			api := newTestAPI(t, stub)
+
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(stub.queries) > 0 {
+					t.Errorf("sent %d queries to elasticsearch, want none", len(stub.queries))
+				}
+				return
+			}
+
+			var results models.SearchResults
+			if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+				t.Fatal(err)
+			}
+
+			if results.Limit != tt.wantLimit || results.Offset != tt.wantOffset {
+				t.Errorf("limit, offset = %d, %d, want %d, %d", results.Limit, results.Offset, tt.wantLimit, tt.wantOffset)
+			}
+			if results.Count != tt.wantCount || results.TotalCount != 3 {
+				t.Errorf("count, total count = %d, %d, want %d, 3", results.Count, results.TotalCount, tt.wantCount)
+			}
+			if query := stub.lastQuery(t); !strings.Contains(query, `"dimensions.name":"`) {
+				t.Errorf("query %s does not filter by dimension", query)
+			}
+		})
+	}
+}
+
+func TestGetDimensions(t *testing.T) {
+	api := newTestAPI(t, &elasticsearchStub{})
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dimensions", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var dimensions models.DimensionsDoc
+	if err := json.Unmarshal(w.Body.Bytes(), &dimensions); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dimensions.Dimensions) != 2 || dimensions.Dimensions[0].Name != "geography" {
+		t.Errorf("dimensions = %+v, want the loaded dimensions", dimensions)
+	}
+}
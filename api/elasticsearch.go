@@ -8,5 +8,7 @@ import (
 
 // Elasticsearcher - An interface used to access elasticsearch
 type Elasticsearcher interface {
+	CountDocuments(ctx context.Context, indexName string) (int, int, error)
+	GetClusterHealth(ctx context.Context) (*models.ClusterHealth, int, error)
 	QueryDatasetSearch(ctx context.Context, indexName string, query interface{}, limit, offset int) (*models.SearchResponse, int, error)
 }
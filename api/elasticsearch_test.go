@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+// elasticsearchStub stands in for elasticsearch, returning the response or error it
+// holds for every search and recording the queries it receives
+type elasticsearchStub struct {
+	response *models.SearchResponse
+	health   *models.ClusterHealth
+	count    int
+	err      error
+
+	// countErr and countStatus are returned when counting documents, so the dataset
+	// index can fail while the cluster is healthy
+	countErr    error
+	countStatus int
+
+	mutex   sync.Mutex
+	indices []string
+	queries []interface{}
+}
+
+func (s *elasticsearchStub) CountDocuments(ctx context.Context, indexName string) (int, int, error) {
+	if s.err != nil {
+		return 0, 0, s.err
+	}
+
+	if s.countErr != nil {
+		return 0, s.countStatus, s.countErr
+	}
+
+	return s.count, 200, nil
+}
+
+func (s *elasticsearchStub) GetClusterHealth(ctx context.Context) (*models.ClusterHealth, int, error) {
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+
+	return s.health, 200, nil
+}
+
+func (s *elasticsearchStub) QueryDatasetSearch(ctx context.Context, indexName string, query interface{}, limit, offset int) (*models.SearchResponse, int, error) {
+	s.mutex.Lock()
+	s.indices = append(s.indices, indexName)
+	s.queries = append(s.queries, query)
+	s.mutex.Unlock()
+
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+
+	if s.response == nil {
+		return &models.SearchResponse{}, 200, nil
+	}
+
+	return s.response, 200, nil
+}
+
+// lastQuery returns the json of the latest query received
+func (s *elasticsearchStub) lastQuery(t *testing.T) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.queries) == 0 {
+		t.Fatal("no query sent to elasticsearch")
+	}
+
+	b, err := json.Marshal(s.queries[len(s.queries)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+// newTestAPI creates a SearchAPI for the stub with the test taxonomy and dimensions
+func newTestAPI(t *testing.T, stub *elasticsearchStub, opts ...Option) *SearchAPI {
+	opts = append([]Option{
+		WithElasticsearch(stub),
+		WithDimensions(testDimensions),
+		WithTaxonomy(testTaxonomy),
+	}, opts...)
+
+	api, err := New(opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return api
+}
+
+var testDimensions = models.DimensionsDoc{
+	Dimensions: []models.DimensionObject{
+		{Label: "Geography", Name: "geography"},
+		{Label: "Sex", Name: "sex"},
+	},
+	TotalCount: 2,
+}
+
+var testTaxonomy = models.Taxonomy{
+	Topics: []models.Topic{
+		{
+			Title:          "Economy",
+			FormattedTitle: "economy",
+			ChildTopics: []models.Topic{
+				{
+					Title:          "Inflation and price indices",
+					FormattedTitle: "inflationandpriceindices",
+					ChildTopics: []models.Topic{
+						{Title: "Consumer prices", FormattedTitle: "consumerprices"},
+					},
+				},
+				{Title: "Gross domestic product", FormattedTitle: "grossdomesticproduct"},
+			},
+		},
+		{Title: "People", FormattedTitle: "people"},
+	},
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	errs "github.com/ONSdigital/dp-census-dataset-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// A list of elasticsearch cluster statuses
+const (
+	clusterGreen  = "green"
+	clusterYellow = "yellow"
+)
+
+// StartHealthCheck checks the health of elasticsearch and the dataset index straight
+// away and then on the interval in the background, so requests to the health
+// endpoint return the result of the latest check without calling elasticsearch
+func (api *SearchAPI) StartHealthCheck(ctx context.Context, interval time.Duration) {
+	api.checkHealth(ctx)
+
+	api.healthDone = make(chan struct{})
+	api.healthWG.Add(1)
+
+	go func() {
+		defer api.healthWG.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				api.checkHealth(ctx)
+			case <-api.healthDone:
+				return
+			}
+		}
+	}()
+}
+
+// StopHealthCheck stops checking the health in the background
+func (api *SearchAPI) StopHealthCheck() {
+	if api.healthDone == nil {
+		return
+	}
+
+	close(api.healthDone)
+	api.healthWG.Wait()
+	api.healthDone = nil
+}
+
+func (api *SearchAPI) checkHealth(ctx context.Context) {
+	health := models.Health{
+		CheckedAt:     time.Now().UTC(),
+		Elasticsearch: api.checkElasticsearch(ctx),
+		DatasetIndex:  api.checkDatasetIndex(ctx),
+	}
+
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	health.Taxonomy.LoadedAt = api.taxonomyLoadedAt
+	health.Dimensions.LoadedAt = api.dimensionsLoadedAt
+	health.Status = worstStatus(health.Elasticsearch.Status, health.DatasetIndex.Status)
+
+	if health.Status != models.HealthOK {
+		log.Event(ctx, "health check failed", log.WARN, log.Data{"health": health})
+	}
+
+	api.health = health
+}
+
+func (api *SearchAPI) checkElasticsearch(ctx context.Context) models.ElasticsearchHealth {
+	clusterHealth, _, err := api.elasticsearch.GetClusterHealth(ctx)
+	if err != nil {
+		return models.ElasticsearchHealth{
+			Status: models.HealthCritical,
+			Error:  err.Error(),
+		}
+	}
+
+	health := models.ElasticsearchHealth{
+		ClusterName:   clusterHealth.ClusterName,
+		ClusterStatus: clusterHealth.Status,
+		NumberOfNodes: clusterHealth.NumberOfNodes,
+	}
+
+	switch clusterHealth.Status {
+	case clusterGreen:
+		health.Status = models.HealthOK
+	case clusterYellow:
+		health.Status = models.HealthWarning
+	default:
+		health.Status = models.HealthCritical
+	}
+
+	return health
+}
+
+func (api *SearchAPI) checkDatasetIndex(ctx context.Context) models.IndexHealth {
+	health := models.IndexHealth{
+		Name: api.datasetIndex,
+	}
+
+	count, status, err := api.elasticsearch.CountDocuments(ctx, api.datasetIndex)
+	if err != nil {
+		health.Status = models.HealthCritical
+		health.Exists = status != http.StatusNotFound && status != 0
+		health.Error = err.Error()
+		return health
+	}
+
+	health.Exists = true
+	health.DocumentCount = count
+	health.Status = models.HealthOK
+
+	if count < 1 {
+		health.Status = models.HealthWarning
+	}
+
+	return health
+}
+
+func worstStatus(statuses ...string) string {
+	severity := map[string]int{
+		models.HealthOK:       0,
+		models.HealthWarning:  1,
+		models.HealthCritical: 2,
+	}
+
+	worst := models.HealthOK
+	for _, status := range statuses {
+		if severity[status] > severity[worst] {
+			worst = status
+		}
+	}
+
+	return worst
+}
+
+func (api *SearchAPI) getHealth(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	setAccessControl(w, http.MethodGet)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	api.mutex.RLock()
+	health := api.health
+	shuttingDown := api.shuttingDown
+	api.mutex.RUnlock()
+
+	if shuttingDown {
+		health.Status = models.HealthCritical
+		health.ShuttingDown = true
+	}
+
+	b, err := json.Marshal(health)
+	if err != nil {
+		log.Event(ctx, "getHealth endpoint: failed to marshal health resource into bytes", log.ERROR, log.Error(err))
+		setErrorCode(w, errs.ErrInternalServer)
+		return
+	}
+
+	switch {
+	case shuttingDown:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case health.Status != models.HealthOK && health.Status != models.HealthWarning:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, "getHealth endpoint: error writing response", log.ERROR, log.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+func TestCheckHealth(t *testing.T) {
+	tests := []struct {
+		name            string
+		stub            *elasticsearchStub
+		wantStatus      string
+		wantIndexStatus string
+		wantIndexExists bool
+		wantCode        int
+	}{
+		{
+			name:            "green cluster with documents",
+			stub:            &elasticsearchStub{health: &models.ClusterHealth{Status: "green"}, count: 10},
+			wantStatus:      models.HealthOK,
+			wantIndexStatus: models.HealthOK,
+			wantIndexExists: true,
+			wantCode:        http.StatusOK,
+		},
+		{
+			name:            "yellow cluster",
+			stub:            &elasticsearchStub{health: &models.ClusterHealth{Status: "yellow"}, count: 10},
+			wantStatus:      models.HealthWarning,
+			wantIndexStatus: models.HealthOK,
+			wantIndexExists: true,
+			wantCode:        http.StatusOK,
+		},
+		{
+			name:            "empty dataset index",
+			stub:            &elasticsearchStub{health: &models.ClusterHealth{Status: "green"}},
+			wantStatus:      models.HealthWarning,
+			wantIndexStatus: models.HealthWarning,
+			wantIndexExists: true,
+			wantCode:        http.StatusOK,
+		},
+		{
+			name:            "red cluster",
+			stub:            &elasticsearchStub{health: &models.ClusterHealth{Status: "red"}, count: 10},
+			wantStatus:      models.HealthCritical,
+			wantIndexStatus: models.HealthOK,
+			wantIndexExists: true,
+			wantCode:        http.StatusInternalServerError,
+		},
+		{
+			name: "missing dataset index",
+			stub: &elasticsearchStub{
+				health:      &models.ClusterHealth{Status: "green"},
+				countErr:    errors.New("index not found"),
+				countStatus: http.StatusNotFound,
+			},
+			wantStatus:      models.HealthCritical,
+			wantIndexStatus: models.HealthCritical,
+			wantCode:        http.StatusInternalServerError,
+		},
+		{
+			name: "dataset index count fails",
+			stub: &elasticsearchStub{
+				health:      &models.ClusterHealth{Status: "green"},
+				countErr:    errors.New("internal error"),
+				countStatus: http.StatusInternalServerError,
+			},
+			wantStatus:      models.HealthCritical,
+			wantIndexStatus: models.HealthCritical,
+			wantIndexExists: true,
+			wantCode:        http.StatusInternalServerError,
+		},
+		{
+			name:            "elasticsearch unreachable",
+			stub:            &elasticsearchStub{err: errors.New("connection refused")},
+			wantStatus:      models.HealthCritical,
+			wantIndexStatus: models.HealthCritical,
+			wantCode:        http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t, tt.stub)
+			api.checkHealth(context.Background())
+
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+
+			var health models.Health
+			if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil {
+				t.Fatal(err)
+			}
+
+			if health.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", health.Status, tt.wantStatus)
+			}
+			if health.DatasetIndex.Status != tt.wantIndexStatus || health.DatasetIndex.Exists != tt.wantIndexExists {
+				t.Errorf("dataset index = %+v, want status %s and exists %t", health.DatasetIndex, tt.wantIndexStatus, tt.wantIndexExists)
+			}
+			if health.CheckedAt.IsZero() || health.Taxonomy.LoadedAt.IsZero() || health.Dimensions.LoadedAt.IsZero() {
+				t.Errorf("health = %+v, want the check and load times set", health)
+			}
+		})
+	}
+}
+
+func TestGetHealthBeforeCheck(t *testing.T) {
+	api := newTestAPI(t, &elasticsearchStub{})
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("code = %d, want %d until the first check", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestGetHealthShuttingDown(t *testing.T) {
+	api := newTestAPI(t, &elasticsearchStub{health: &models.ClusterHealth{Status: "green"}, count: 10})
+	api.checkHealth(context.Background())
+	api.SetShuttingDown()
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("code = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+
+	var health models.Health
+	if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil {
+		t.Fatal(err)
+	}
+
+	if health.Status != models.HealthCritical || !health.ShuttingDown {
+		t.Errorf("status, shutting down = %s, %t, want %s, true", health.Status, health.ShuttingDown, models.HealthCritical)
+	}
+}
+
+func TestWorstStatus(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{statuses: nil, want: models.HealthOK},
+		{statuses: []string{models.HealthOK, models.HealthWarning}, want: models.HealthWarning},
+		{statuses: []string{models.HealthCritical, models.HealthWarning}, want: models.HealthCritical},
+	}
+
+	for _, tt := range tests {
+		if got := worstStatus(tt.statuses...); got != tt.want {
+			t.Errorf("worstStatus(%v) = %s, want %s", tt.statuses, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option configures a SearchAPI created by New
+type Option func(*SearchAPI)
+
+// WithDatasetIndex sets the elasticsearch index that datasets are searched in
+func WithDatasetIndex(datasetIndex string) Option {
+	return func(api *SearchAPI) {
+		api.datasetIndex = datasetIndex
+	}
+}
+
+// WithDimensions sets the list of dimensions used to serve and validate requests
+func WithDimensions(dimensions models.DimensionsDoc) Option {
+	return func(api *SearchAPI) {
+		api.dimensions = dimensions
+	}
+}
+
+// WithTaxonomy sets the taxonomy used to serve and validate requests
+func WithTaxonomy(taxonomy models.Taxonomy) Option {
+	return func(api *SearchAPI) {
+		api.taxonomy = taxonomy
+	}
+}
+
+// WithMaxOffset sets the maximum offset of the results returned by a search
+func WithMaxOffset(maxOffset int) Option {
+	return func(api *SearchAPI) {
+		api.defaultMaxResults = maxOffset
+	}
+}
+
+// WithElasticsearch sets the client used to query elasticsearch, it is required
+func WithElasticsearch(elasticsearch Elasticsearcher) Option {
+	return func(api *SearchAPI) {
+		api.elasticsearch = elasticsearch
+	}
+}
+
+// WithMetrics records the requests served and the loaded taxonomy and dimensions in
+// metrics registered with reg, no metrics are recorded without it, the caller is
+// responsible for exposing them and each SearchAPI needs a registerer of its own
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(api *SearchAPI) {
+		api.metricsRegisterer = reg
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	errs "github.com/ONSdigital/dp-census-dataset-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+// parsePage reads the limit and offset query parameters, defaulting either when not
+// set, and validates them against the maximum offset, reducing the limit if needed
+func (api *SearchAPI) parsePage(r *http.Request) (*models.PageVariables, error) {
+	var err error
+
+	limit := defaultLimit
+	if requestedLimit := r.FormValue("limit"); requestedLimit != "" {
+		limit, err = strconv.Atoi(requestedLimit)
+		if err != nil {
+			return nil, errs.ErrParsingQueryParameters
+		}
+	}
+
+	offset := defaultOffset
+	if requestedOffset := r.FormValue("offset"); requestedOffset != "" {
+		offset, err = strconv.Atoi(requestedOffset)
+		if err != nil {
+			return nil, errs.ErrParsingQueryParameters
+		}
+	}
+
+	page := &models.PageVariables{
+		DefaultMaxResults: api.defaultMaxResults,
+		Limit:             limit,
+		Offset:            offset,
+	}
+
+	if err = page.Validate(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// newSearchResults builds a page of results from the response to a dataset search,
+// keeping any highlighted matches against each dataset
+func newSearchResults(page *models.PageVariables, response *models.SearchResponse) *models.SearchResults {
+	searchResults := &models.SearchResults{
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		TotalCount: response.Hits.Total,
+		Items:      []models.SearchResult{},
+	}
+
+	for _, result := range response.Hits.HitList {
+		doc := result.Source
+		doc.Matches = result.Matches
+
+		searchResults.Items = append(searchResults.Items, doc)
+	}
+
+	searchResults.Count = len(searchResults.Items)
+
+	return searchResults
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 
 	errs "github.com/ONSdigital/dp-census-dataset-search-api/apierrors"
 	"github.com/ONSdigital/dp-census-dataset-search-api/models"
@@ -10,6 +13,11 @@ import (
 	"github.com/gorilla/mux"
 )
 
+const (
+	defaultTopicDepth  = 1
+	maximumTopDatasets = 50
+)
+
 func (api *SearchAPI) getTaxonomy(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	setAccessControl(w, http.MethodGet)
@@ -19,29 +27,60 @@ func (api *SearchAPI) getTaxonomy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Event(ctx, "getTaxonomy endpoint: incoming request", log.INFO)
+	requestedIncludeCounts := r.FormValue("include_counts")
+	logData := log.Data{"requested_include_counts": requestedIncludeCounts}
+
+	log.Event(ctx, "getTaxonomy endpoint: incoming request", log.INFO, logData)
 
-	b, err := json.Marshal(api.taxonomy)
+	includeCounts, err := parseIncludeCounts(requestedIncludeCounts)
 	if err != nil {
-		log.Event(ctx, "getTaxonomy endpoint: failed to marshal taxonomy resource into bytes", log.ERROR, log.Error(err))
+		log.Event(ctx, "getTaxonomy endpoint: request include_counts parameter error", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
+
+	taxonomy := api.currentTaxonomy()
+
+	if includeCounts {
+		counts, status, err := api.getTopicCounts(ctx)
+		if err != nil {
+			logData["elasticsearch_status"] = status
+			log.Event(ctx, "getTaxonomy endpoint: failed to get topic counts", log.ERROR, log.Error(err), logData)
+			setErrorCode(w, err)
+			return
+		}
+
+		taxonomy = models.Taxonomy{
+			Topics: copyTopics(taxonomy.Topics, -1, counts),
+		}
+	}
+
+	b, err := json.Marshal(taxonomy)
+	if err != nil {
+		log.Event(ctx, "getTaxonomy endpoint: failed to marshal taxonomy resource into bytes", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, errs.ErrInternalServer)
+		return
 	}
 
 	_, err = w.Write(b)
 	if err != nil {
-		log.Event(ctx, "getTaxonomy endpoint: error writing response", log.ERROR, log.Error(err))
+		log.Event(ctx, "getTaxonomy endpoint: error writing response", log.ERROR, log.Error(err), logData)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 
-	log.Event(ctx, "getTaxonomy endpoint: successfully retreived taxonomy", log.INFO)
+	log.Event(ctx, "getTaxonomy endpoint: successfully retreived taxonomy", log.INFO, logData)
 }
 
-// Topic ...
+// Topic represents a single topic within the taxonomy and its position in the hierarchy
 type Topic struct {
-	ParentTopic string   `json:"parent_topic,omitempty"`
-	Title       string   `json:"title"`
-	Topic       string   `json:"topic"`
-	ChildTopics []string `json:"child_topics,omitempty"`
+	Ancestors    []models.Topic        `json:"ancestors,omitempty"`
+	ParentTopic  string                `json:"parent_topic,omitempty"`
+	Level        int                   `json:"level"`
+	Title        string                `json:"title"`
+	Topic        string                `json:"topic"`
+	DatasetCount *int                  `json:"dataset_count,omitempty"`
+	ChildTopics  []models.Topic        `json:"child_topics,omitempty"`
+	Datasets     []models.SearchResult `json:"datasets,omitempty"`
 }
 
 func (api *SearchAPI) getTopic(w http.ResponseWriter, r *http.Request) {
@@ -55,36 +94,47 @@ func (api *SearchAPI) getTopic(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	topic := vars["topic"]
-	logData := log.Data{"topic": topic}
+	requestedDepth := r.FormValue("depth")
+	requestedIncludeCounts := r.FormValue("include_counts")
+	requestedDatasets := r.FormValue("datasets")
+	logData := log.Data{
+		"topic":                    topic,
+		"requested_depth":          requestedDepth,
+		"requested_include_counts": requestedIncludeCounts,
+		"requested_datasets":       requestedDatasets,
+	}
 
 	log.Event(ctx, "getTopic endpoint: incoming request", log.INFO, logData)
 
-	var result *Topic
-	var hasValidTopic bool
-	for _, taxonomy := range api.taxonomy.Topics {
-		if topic == taxonomy.FormattedTitle {
-			hasValidTopic = true
-
-			var childTopics []string
-			for _, childTopic := range taxonomy.ChildTopics {
-				childTopics = append(childTopics, childTopic.FormattedTitle)
-			}
-
-			result = &Topic{
-				Title:       taxonomy.Title,
-				Topic:       topic,
-				ChildTopics: childTopics,
-			}
-
-			break
+	depth := defaultTopicDepth
+	if requestedDepth != "" {
+		var err error
+		depth, err = strconv.Atoi(requestedDepth)
+		if err != nil || depth < 1 {
+			log.Event(ctx, "getTopic endpoint: request depth parameter error", log.ERROR, log.Error(errs.ErrInvalidDepth), logData)
+			setErrorCode(w, errs.ErrInvalidDepth)
+			return
 		}
+	}
+
+	includeCounts, err := parseIncludeCounts(requestedIncludeCounts)
+	if err != nil {
+		log.Event(ctx, "getTopic endpoint: request include_counts parameter error", log.ERROR, log.Error(err), logData)
+		setErrorCode(w, err)
+		return
+	}
 
-		result, hasValidTopic = checkChildTopics(taxonomy, topic)
-		if hasValidTopic {
-			break
+	var datasets int
+	if requestedDatasets != "" {
+		datasets, err = strconv.Atoi(requestedDatasets)
+		if err != nil || datasets < 1 || datasets > maximumTopDatasets {
+			log.Event(ctx, "getTopic endpoint: request datasets parameter error", log.ERROR, log.Error(errs.ErrInvalidTopDatasets), logData)
+			setErrorCode(w, errs.ErrInvalidTopDatasets)
+			return
 		}
 	}
 
+	taxonomyTopic, ancestors, hasValidTopic := findTopic(api.currentTaxonomy().Topics, topic, nil)
 	if !hasValidTopic {
 		err := errs.ErrTopicNotFound
 		log.Event(ctx, "getTopic endpoint: failed to find topic", log.ERROR, log.Error(err), logData)
@@ -92,10 +142,57 @@ func (api *SearchAPI) getTopic(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	level := len(ancestors) + 1
+
+	var counts topicCounts
+	if includeCounts {
+		var status int
+		counts, status, err = api.getTopicCounts(ctx)
+		if err != nil {
+			logData["elasticsearch_status"] = status
+			log.Event(ctx, "getTopic endpoint: failed to get topic counts", log.ERROR, log.Error(err), logData)
+			setErrorCode(w, err)
+			return
+		}
+
+		for i := range ancestors {
+			ancestors[i].DatasetCount = counts.get(ancestors[i].FormattedTitle)
+		}
+	}
+
+	result := &Topic{
+		Ancestors:    ancestors,
+		Level:        level,
+		Title:        taxonomyTopic.Title,
+		Topic:        taxonomyTopic.FormattedTitle,
+		DatasetCount: counts.get(taxonomyTopic.FormattedTitle),
+		ChildTopics:  copyTopics(taxonomyTopic.ChildTopics, depth, counts),
+	}
+
+	if datasets > 0 {
+		path := make([]string, 0, level)
+		for _, ancestor := range ancestors {
+			path = append(path, ancestor.FormattedTitle)
+		}
+		path = append(path, taxonomyTopic.FormattedTitle)
+
+		result.Datasets, err = api.getTopDatasets(ctx, path, datasets)
+		if err != nil {
+			log.Event(ctx, "getTopic endpoint: failed to get datasets for topic", log.ERROR, log.Error(err), logData)
+			setErrorCode(w, err)
+			return
+		}
+	}
+
+	if len(ancestors) > 0 {
+		result.ParentTopic = ancestors[len(ancestors)-1].FormattedTitle
+	}
+
 	b, err := json.Marshal(result)
 	if err != nil {
 		log.Event(ctx, "getTopic endpoint: failed to marshal topic resource into bytes", log.ERROR, log.Error(err), logData)
 		setErrorCode(w, errs.ErrInternalServer)
+		return
 	}
 
 	_, err = w.Write(b)
@@ -107,32 +204,141 @@ func (api *SearchAPI) getTopic(w http.ResponseWriter, r *http.Request) {
 	log.Event(ctx, "getTopic endpoint: successfully retrieved topic", log.INFO, logData)
 }
 
-func checkChildTopics(taxonomy models.Topic, topic string) (result *Topic, hasValidTopic bool) {
+// findTopic walks the taxonomy looking for a topic by its filterable title, returning the
+// topic along with the chain of ancestors from the top level down to its parent
+func findTopic(topics []models.Topic, topic string, ancestors []models.Topic) (*models.Topic, []models.Topic, bool) {
+	for i := range topics {
+		if topics[i].FormattedTitle == topic {
+			return &topics[i], ancestors, true
+		}
 
-	for _, childTopic := range taxonomy.ChildTopics {
-		if topic == childTopic.FormattedTitle {
-			hasValidTopic = true
+		breadcrumb := make([]models.Topic, len(ancestors), len(ancestors)+1)
+		copy(breadcrumb, ancestors)
+		breadcrumb = append(breadcrumb, models.Topic{
+			Title:          topics[i].Title,
+			FormattedTitle: topics[i].FormattedTitle,
+		})
 
-			var childTopics []string
-			for _, childTopic := range childTopic.ChildTopics {
-				childTopics = append(childTopics, childTopic.FormattedTitle)
-			}
+		if result, resultAncestors, ok := findTopic(topics[i].ChildTopics, topic, breadcrumb); ok {
+			return result, resultAncestors, true
+		}
+	}
 
-			result = &Topic{
-				ParentTopic: taxonomy.FormattedTitle,
-				Title:       childTopic.Title,
-				Topic:       topic,
-				ChildTopics: childTopics,
-			}
+	return nil, nil, false
+}
 
-			break
-		}
+// copyTopics copies the topics, keeping only the number of levels set by depth (a negative depth
+// keeps every level) and setting the dataset count against each topic when counts are provided
+func copyTopics(topics []models.Topic, depth int, counts topicCounts) []models.Topic {
+	if depth == 0 || len(topics) == 0 {
+		return nil
+	}
 
-		result, hasValidTopic = checkChildTopics(childTopic, topic)
-		if hasValidTopic {
-			break
-		}
+	var copiedTopics []models.Topic
+	for _, topic := range topics {
+		copiedTopics = append(copiedTopics, models.Topic{
+			Title:          topic.Title,
+			FormattedTitle: topic.FormattedTitle,
+			DatasetCount:   counts.get(topic.FormattedTitle),
+			ChildTopics:    copyTopics(topic.ChildTopics, depth-1, counts),
+		})
+	}
+
+	return copiedTopics
+}
+
+// topicCounts holds the number of datasets stored against each topic or any of its descendants
+type topicCounts map[string]int
+
+// get returns the number of datasets for a topic, or nil if counts were not requested
+func (counts topicCounts) get(topic string) *int {
+	if counts == nil {
+		return nil
+	}
+
+	count := counts[topic]
+
+	return &count
+}
+
+// getTopicCounts runs a terms aggregation against the topics field to count
+// the number of datasets stored against every topic in the index
+func (api *SearchAPI) getTopicCounts(ctx context.Context) (topicCounts, int, error) {
+	query := &models.Body{
+		Aggregations: map[string]models.Aggregation{
+			models.TopicsField: {
+				Terms: &models.TermsAggregation{
+					Field: models.TopicsField,
+					Size:  countTopics(api.currentTaxonomy().Topics),
+				},
+			},
+		},
+		Query: models.Query{
+			MatchAll: &models.Object{},
+		},
+		Sort: []models.Scores{
+			{
+				Score: models.Score{
+					Order: "desc",
+				},
+			},
+		},
+	}
+
+	response, status, err := api.elasticsearch.QueryDatasetSearch(ctx, api.datasetIndex, query, 0, 0)
+	if err != nil {
+		return nil, status, err
+	}
+
+	counts := make(topicCounts)
+	for _, bucket := range response.Aggregations[models.TopicsField].Buckets {
+		counts[bucket.Key] = bucket.DocCount
+	}
+
+	return counts, status, nil
+}
+
+// getTopDatasets retrieves the first n datasets stored against the topic at the end of the
+// path or any of its descendants
+func (api *SearchAPI) getTopDatasets(ctx context.Context, path []string, n int) ([]models.SearchResult, error) {
+	filters := []models.Filter{
+		{
+			Term: map[string]string{models.TopicPathHierarchyField: strings.Join(path, models.TopicPathSeparator)},
+		},
+	}
+
+	response, _, err := api.elasticsearch.QueryDatasetSearch(ctx, api.datasetIndex, buildFilterQuery(filters, n, 0), n, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	datasets := []models.SearchResult{}
+	for _, result := range response.Hits.HitList {
+		datasets = append(datasets, result.Source)
+	}
+
+	return datasets, nil
+}
+
+// countTopics returns the total number of topics across all levels of the taxonomy
+func countTopics(topics []models.Topic) int {
+	count := len(topics)
+	for _, topic := range topics {
+		count += countTopics(topic.ChildTopics)
+	}
+
+	return count
+}
+
+func parseIncludeCounts(includeCounts string) (bool, error) {
+	if includeCounts == "" {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(includeCounts)
+	if err != nil {
+		return false, errs.ErrParsingBooleanParameter
 	}
 
-	return
+	return value, nil
 }
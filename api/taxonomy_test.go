@@ -0,0 +1,236 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+// getTopicResponse requests the target from the api, failing the test unless the status
+// is as expected, and decodes a successful response into a Topic
+func getTopicResponse(t *testing.T, api *SearchAPI, target string, wantStatus int) *Topic {
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+	if w.Code != wantStatus {
+		t.Fatalf("status = %d, want %d: %s", w.Code, wantStatus, w.Body.String())
+	}
+	if wantStatus != http.StatusOK {
+		return nil
+	}
+
+	var topic Topic
+	if err := json.Unmarshal(w.Body.Bytes(), &topic); err != nil {
+		t.Fatal(err)
+	}
+
+	return &topic
+}
+
+// titles lists the filterable titles of the ancestors and then of the child topics nested to
+// any depth, depth first, with each child prefixed by the titles above it
+func titles(topic *Topic) []string {
+	var list []string
+	for _, ancestor := range topic.Ancestors {
+		list = append(list, "ancestor:"+ancestor.FormattedTitle)
+	}
+
+	var walk func(prefix string, topics []models.Topic)
+	walk = func(prefix string, topics []models.Topic) {
+		for _, child := range topics {
+			list = append(list, prefix+child.FormattedTitle)
+			walk(prefix+child.FormattedTitle+">", child.ChildTopics)
+		}
+	}
+	walk("child:", topic.ChildTopics)
+
+	return list
+}
+
+func TestGetTopic(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantLevel  int
+		wantParent string
+		wantTitles []string
+	}{
+		{
+			name:       "top level topic",
+			target:     "/taxonomy/economy",
+			wantLevel:  1,
+			wantTitles: []string{"child:inflationandpriceindices", "child:grossdomesticproduct"},
+		},
+		{
+			name:       "nested topic",
+			target:     "/taxonomy/inflationandpriceindices",
+			wantLevel:  2,
+			wantParent: "economy",
+			wantTitles: []string{"ancestor:economy", "child:consumerprices"},
+		},
+		{
+			name:       "deepest topic",
+			target:     "/taxonomy/consumerprices",
+			wantLevel:  3,
+			wantParent: "inflationandpriceindices",
+			wantTitles: []string{"ancestor:economy", "ancestor:inflationandpriceindices"},
+		},
+		{
+			name:      "child topics to a depth",
+			target:    "/taxonomy/economy?depth=2",
+			wantLevel: 1,
+			wantTitles: []string{
+				"child:inflationandpriceindices",
+				"child:inflationandpriceindices>consumerprices",
+				"child:grossdomesticproduct",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topic := getTopicResponse(t, newTestAPI(t, &elasticsearchStub{}), tt.target, http.StatusOK)
+
+			if topic.Level != tt.wantLevel || topic.ParentTopic != tt.wantParent {
+				t.Errorf("level, parent = %d, %q, want %d, %q", topic.Level, topic.ParentTopic, tt.wantLevel, tt.wantParent)
+			}
+			if got := titles(topic); !reflect.DeepEqual(got, tt.wantTitles) {
+				t.Errorf("topics = %v, want %v", got, tt.wantTitles)
+			}
+		})
+	}
+}
+
+func TestGetTopicErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{name: "unknown topic", target: "/taxonomy/unknown", wantStatus: http.StatusNotFound},
+		{name: "zero depth", target: "/taxonomy/economy?depth=0", wantStatus: http.StatusBadRequest},
+		{name: "invalid depth", target: "/taxonomy/economy?depth=all", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getTopicResponse(t, newTestAPI(t, &elasticsearchStub{}), tt.target, tt.wantStatus)
+		})
+	}
+}
+
+func TestTopicCounts(t *testing.T) {
+	stub := &elasticsearchStub{
+		response: &models.SearchResponse{
+			Aggregations: map[string]models.AggregationResult{
+				models.TopicsField: {
+					Buckets: []models.Bucket{
+						{Key: "economy", DocCount: 5},
+						{Key: "inflationandpriceindices", DocCount: 3},
+						{Key: "consumerprices", DocCount: 2},
+					},
+				},
+			},
+		},
+	}
+
+	topic := getTopicResponse(t, newTestAPI(t, stub), "/taxonomy/inflationandpriceindices?include_counts=true", http.StatusOK)
+
+	counts := map[string]int{topic.Topic: *topic.DatasetCount}
+	for _, ancestor := range topic.Ancestors {
+		counts[ancestor.FormattedTitle] = *ancestor.DatasetCount
+	}
+	for _, child := range topic.ChildTopics {
+		counts[child.FormattedTitle] = *child.DatasetCount
+	}
+
+	want := map[string]int{"economy": 5, "inflationandpriceindices": 3, "consumerprices": 2}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+
+	w := httptest.NewRecorder()
+	newTestAPI(t, stub).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/taxonomy?include_counts=true", nil))
+
+	var taxonomy models.Taxonomy
+	if err := json.Unmarshal(w.Body.Bytes(), &taxonomy); err != nil {
+		t.Fatal(err)
+	}
+
+	people := taxonomy.Topics[1]
+	if people.DatasetCount == nil || *people.DatasetCount != 0 {
+		t.Errorf("people count = %v, want 0 for a topic without datasets", people.DatasetCount)
+	}
+	if grossDomesticProduct := taxonomy.Topics[0].ChildTopics[1]; grossDomesticProduct.DatasetCount == nil {
+		t.Error("counts missing below the top level of the taxonomy")
+	}
+}
+
+func TestTopicWithoutCounts(t *testing.T) {
+	stub := &elasticsearchStub{}
+	topic := getTopicResponse(t, newTestAPI(t, stub), "/taxonomy/economy", http.StatusOK)
+
+	if topic.DatasetCount != nil || topic.Datasets != nil {
+		t.Errorf("topic = %+v, want no counts or datasets unless requested", topic)
+	}
+	if len(stub.queries) > 0 {
+		t.Errorf("sent %d queries to elasticsearch, want none", len(stub.queries))
+	}
+}
+
+func TestTopDatasets(t *testing.T) {
+	stub := &elasticsearchStub{
+		response: &models.SearchResponse{
+			Hits: models.Hits{
+				Total: 1,
+				HitList: []models.HitList{
+					{Source: models.SearchResult{Alias: "cpih01", Title: "CPIH"}},
+				},
+			},
+		},
+	}
+
+	topic := getTopicResponse(t, newTestAPI(t, stub), "/taxonomy/inflationandpriceindices?datasets=5", http.StatusOK)
+
+	if len(topic.Datasets) != 1 || topic.Datasets[0].Alias != "cpih01" {
+		t.Errorf("datasets = %+v, want the datasets from elasticsearch", topic.Datasets)
+	}
+
+	var query models.Body
+	if err := json.Unmarshal([]byte(stub.lastQuery(t)), &query); err != nil {
+		t.Fatal(err)
+	}
+	if query.Size != 5 || query.Query.Bool == nil || len(query.Query.Bool.Filter) != 1 {
+		t.Fatalf("query = %s, want 5 datasets filtered by topic", stub.lastQuery(t))
+	}
+	if path := query.Query.Bool.Filter[0].Term[models.TopicPathHierarchyField]; path != "economy/inflationandpriceindices" {
+		t.Errorf("filtered by topic path %q, want the path down to the topic", path)
+	}
+}
+
+func TestTopicCountsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{name: "invalid include counts", target: "/taxonomy/economy?include_counts=yes"},
+		{name: "too many datasets", target: "/taxonomy/economy?datasets=51"},
+		{name: "no datasets", target: "/taxonomy/economy?datasets=0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getTopicResponse(t, newTestAPI(t, &elasticsearchStub{}), tt.target, http.StatusBadRequest)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	newTestAPI(t, &elasticsearchStub{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/taxonomy?include_counts=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("taxonomy status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -1,14 +1,21 @@
 package apierrors
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // A list of error messages for Search API
 var (
 	ErrBadSearchQuery          = errors.New("bad query sent to elasticsearch index")
+	ErrDimensionNotFound       = errors.New("Dimension not found")
 	ErrEmptySearchTerm         = errors.New("empty search term")
 	ErrIndexNotFound           = errors.New("search index not found")
 	ErrInternalServer          = errors.New("internal server error")
+	ErrInvalidDepth            = errors.New("invalid depth parameter, value must be a positive integer")
+	ErrInvalidTopDatasets      = errors.New("invalid datasets parameter, value must be an integer between 1 and 50")
 	ErrMarshallingQuery        = errors.New("failed to marshal query to bytes for request body to send to elastic")
+	ErrParsingBooleanParameter = errors.New("failed to parse query parameters, value must be a boolean")
 	ErrParsingQueryParameters  = errors.New("failed to parse query parameters, values must be an integer")
 	ErrTooManyDimensionFilters = errors.New("Too many dimension filters, limited to a maximum of 10")
 	ErrTooManyTopicFilters     = errors.New("Too many topic filters, limited to a maximum of 10")
@@ -17,13 +24,27 @@ var (
 	ErrUnexpectedStatusCode    = errors.New("unexpected status code from elastic api")
 
 	NotFoundMap = map[error]bool{
-		ErrTopicNotFound: true,
+		ErrDimensionNotFound: true,
+		ErrTopicNotFound:     true,
 	}
 
 	BadRequestMap = map[error]bool{
 		ErrEmptySearchTerm:         true,
+		ErrInvalidDepth:            true,
+		ErrInvalidTopDatasets:      true,
+		ErrParsingBooleanParameter: true,
 		ErrParsingQueryParameters:  true,
 		ErrTooManyDimensionFilters: true,
 		ErrTooManyTopicFilters:     true,
 	}
 )
+
+// UnavailableError is returned when elasticsearch is unhealthy and requests are
+// failing fast, RetryAfter is how long until elasticsearch will next be called
+type UnavailableError struct {
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return "elasticsearch is unavailable, please retry later"
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/config"
+)
+
+// errUsage is returned when a command is run with missing, unknown or invalid arguments,
+// once the usage of the command has been printed
+var errUsage = errors.New("invalid usage")
+
+// command is a command of the dataset-search binary, which either runs or holds a list
+// of subcommands
+type command struct {
+	name        string
+	description string
+	run         func(ctx context.Context, cfg *config.Config, args []string) error
+	subcommands []*command
+}
+
+// rootCommand holds every command, running the binary without a command serves the api
+var rootCommand = &command{
+	name: "dataset-search",
+	subcommands: []*command{
+		serveCommand,
+		indexCommand,
+		datasetsCommand,
+		taxonomyCommand,
+	},
+}
+
+// execute runs the command, or the subcommand named by the first argument
+func (c *command) execute(ctx context.Context, cfg *config.Config, path string, args []string) error {
+	if c.run != nil {
+		return c.run(ctx, cfg, args)
+	}
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		c.printUsage(path)
+		return errUsage
+	}
+
+	for _, subcommand := range c.subcommands {
+		if subcommand.name == args[0] {
+			return subcommand.execute(ctx, cfg, path+" "+subcommand.name, args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", path+" "+args[0])
+	c.printUsage(path)
+
+	return errUsage
+}
+
+func (c *command) printUsage(path string) {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [arguments]\n\nCommands:\n", path)
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	for _, subcommand := range c.subcommands {
+		fmt.Fprintf(w, "  %s\t%s\n", subcommand.name, subcommand.description)
+	}
+	w.Flush()
+
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", path)
+}
+
+// newFlagSet creates the flag set of a command, the arguments describe any positional
+// arguments the command takes after its flags
+func newFlagSet(path, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s\n\nFlags:\n", strings.TrimSpace(path+" [flags] "+arguments))
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parseFlags parses the arguments of a command, checking the number of positional
+// arguments is between min and max, a max of -1 allows any number
+func parseFlags(fs *flag.FlagSet, args []string, min, max int) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		fmt.Fprintf(fs.Output(), "unexpected number of arguments: %d\n\n", fs.NArg())
+		fs.Usage()
+		return errUsage
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/config"
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/cmddatasets"
+	es "github.com/ONSdigital/dp-census-dataset-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/upload"
+)
+
+// defaultDatasetsFilename is the csv file written by the retrieve-cmd-datasets script,
+// relative to the root of the repository
+const defaultDatasetsFilename = "scripts/cmd-datasets.csv"
+
+var datasetsCommand = &command{
+	name:        "datasets",
+	description: "fetch, upload and validate the datasets that are searched",
+	subcommands: []*command{
+		{
+			name:        "fetch",
+			description: "retrieve the cmd datasets from mongo or the dataset api and write them to a csv file",
+			run:         fetchDatasets,
+		},
+		{
+			name:        "upload",
+			description: "upload the datasets in a file to a new index behind the dataset alias",
+			run:         uploadDatasets,
+		},
+		{
+			name:        "validate",
+			description: "validate the datasets in a file and output a report, without writing to elasticsearch",
+			run:         validateDatasets,
+		},
+	},
+}
+
+func fetchDatasets(ctx context.Context, cfg *config.Config, args []string) error {
+	opts := cmddatasets.DefaultOptions()
+	opts.Filename = defaultDatasetsFilename
+
+	fs := newFlagSet("dataset-search datasets fetch", "")
+	opts.Register(fs)
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	return cmddatasets.Run(ctx, opts)
+}
+
+func uploadDatasets(ctx context.Context, cfg *config.Config, args []string) error {
+	opts := uploadOptions(cfg)
+
+	fs := newFlagSet("dataset-search datasets upload", "")
+	opts.Register(fs)
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		return upload.Run(ctx, nil, opts)
+	}
+
+	esAPI, err := es.NewAPIFromConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer esAPI.Close()
+
+	return upload.Run(ctx, esAPI, opts)
+}
+
+func validateDatasets(ctx context.Context, cfg *config.Config, args []string) error {
+	opts := uploadOptions(cfg)
+	opts.DryRun = true
+
+	fs := newFlagSet("dataset-search datasets validate", "")
+	fs.StringVar(&opts.Filename, "filename", opts.Filename, "the csv, json or ndjson filename that contains the datasets to validate")
+	fs.StringVar(&opts.Format, "format", opts.Format, "the format of the file, one of csv, json or ndjson, defaults to the format matching the file extension")
+	fs.StringVar(&opts.TaxonomyFilename, "taxonomy-filename", opts.TaxonomyFilename, "the file locataion and name that contains the taxonomy hierarchy")
+	fs.StringVar(&opts.TopicMode, "topic-mode", opts.TopicMode, "how datasets with a topic missing from the taxonomy are handled, strict fails the validation while lenient only reports them")
+	fs.StringVar(&opts.TopicOverridesFilename, "topic-overrides", opts.TopicOverridesFilename, "a json file mapping topics missing from the taxonomy to topics in the taxonomy")
+	fs.StringVar(&opts.ReportFilename, "report", opts.ReportFilename, "the file the report is written to, defaults to stdout")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	return upload.Run(ctx, nil, opts)
+}
+
+// uploadOptions returns the default upload options with the alias, indices and files
+// that the api is configured with
+func uploadOptions(cfg *config.Config) upload.Options {
+	opts := upload.DefaultOptions()
+	opts.Alias = cfg.DatasetIndex
+	opts.DimensionsFilename = cfg.DimensionsFilename
+	opts.Filename = defaultDatasetsFilename
+	opts.MetadataIndex = cfg.MetadataIndex
+	opts.TaxonomyFilename = cfg.TaxonomyFilename
+
+	return opts
+}
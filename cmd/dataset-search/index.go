@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/config"
+	es "github.com/ONSdigital/dp-census-dataset-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/log.go/log"
+)
+
+const defaultMappingsFile = "dataset-mappings.json"
+
+var indexCommand = &command{
+	name:        "index",
+	description: "create, delete, alias and inspect elasticsearch indices",
+	subcommands: []*command{
+		{
+			name:        "create",
+			description: "create an index with the dataset or metadata mappings",
+			run:         createIndex,
+		},
+		{
+			name:        "delete",
+			description: "delete an index, refusing to delete an index behind the dataset alias",
+			run:         deleteIndex,
+		},
+		{
+			name:        "alias",
+			description: "show the indices behind the dataset alias, or point the alias at an index",
+			run:         aliasIndex,
+		},
+		{
+			name:        "stats",
+			description: "show the number of documents in each index created for the dataset alias",
+			run:         indexStats,
+		},
+	},
+}
+
+func createIndex(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("dataset-search index create", "<index>")
+	mappingsFile := fs.String("mappings", defaultMappingsFile, "the mappings the index is created with, one of dataset-mappings.json or metadata-mappings.json")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	esAPI, err := es.NewAPIFromConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer esAPI.Close()
+
+	indexName := fs.Arg(0)
+	logData := log.Data{"index": indexName, "mappings": *mappingsFile}
+
+	if status, err := esAPI.CreateSearchIndex(ctx, indexName, *mappingsFile); err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	log.Event(ctx, "created index", log.INFO, logData)
+
+	return nil
+}
+
+func deleteIndex(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("dataset-search index delete", "<index>")
+	alias := fs.String("alias", cfg.DatasetIndex, "the alias the index is checked against before it is deleted")
+	force := fs.Bool("force", false, "delete the index even if the alias points to it")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	esAPI, err := es.NewAPIFromConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer esAPI.Close()
+
+	indexName := fs.Arg(0)
+	logData := log.Data{"index": indexName, "alias": *alias}
+
+	if !*force {
+		indices, status, err := esAPI.GetAliasIndices(ctx, *alias)
+		if err != nil {
+			logData["status"] = status
+			log.Event(ctx, "failed to get indices for alias", log.ERROR, log.Error(err), logData)
+			return err
+		}
+
+		for _, index := range indices {
+			if index == indexName {
+				err = fmt.Errorf("index %s is behind alias %s, use -force to delete it", indexName, *alias)
+				log.Event(ctx, "refusing to delete index in use", log.ERROR, log.Error(err), logData)
+				return err
+			}
+		}
+	}
+
+	if status, err := esAPI.DeleteSearchIndex(ctx, indexName); err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to delete index", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	log.Event(ctx, "deleted index", log.INFO, logData)
+
+	return nil
+}
+
+func aliasIndex(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("dataset-search index alias", "[index]")
+	alias := fs.String("alias", cfg.DatasetIndex, "the alias to show or point at the index")
+	if err := parseFlags(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	esAPI, err := es.NewAPIFromConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer esAPI.Close()
+
+	logData := log.Data{"alias": *alias}
+
+	indices, status, err := esAPI.GetAliasIndices(ctx, *alias)
+	if err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to get indices for alias", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		for _, index := range indices {
+			fmt.Println(index)
+		}
+		return nil
+	}
+
+	newIndex := fs.Arg(0)
+	logData["new_index"] = newIndex
+	logData["old_indices"] = indices
+
+	exists, status, err := esAPI.IndexExists(ctx, newIndex)
+	if err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to check index exists", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	if !exists {
+		err = fmt.Errorf("index %s does not exist", newIndex)
+		log.Event(ctx, "failed to point alias at index", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	if status, err = esAPI.SwapAlias(ctx, *alias, newIndex, indices, nil); err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to swap alias", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	log.Event(ctx, "alias now points at index", log.INFO, logData)
+
+	return nil
+}
+
+func indexStats(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("dataset-search index stats", "")
+	alias := fs.String("alias", cfg.DatasetIndex, "the alias whose indices are shown")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	esAPI, err := es.NewAPIFromConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer esAPI.Close()
+
+	logData := log.Data{"alias": *alias}
+
+	current, status, err := esAPI.GetAliasIndices(ctx, *alias)
+	if err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to get indices for alias", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	inUse := make(map[string]bool)
+	for _, index := range current {
+		inUse[index] = true
+	}
+
+	// the pattern also matches an index created before uploads used aliases, which has
+	// the same name as the alias
+	indices, status, err := esAPI.ListIndices(ctx, *alias+"*")
+	if err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to list indices", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	// an index pointed at by hand need not match the pattern
+	listed := make(map[string]bool)
+	for _, index := range indices {
+		listed[index] = true
+	}
+
+	for _, index := range current {
+		if !listed[index] {
+			indices = append(indices, index)
+		}
+	}
+	sort.Strings(indices)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tDOCUMENTS\tALIAS")
+
+	for _, index := range indices {
+		count, status, err := esAPI.CountDocuments(ctx, index)
+		if err != nil {
+			log.Event(ctx, "failed to count documents in index", log.ERROR, log.Error(err), log.Data{"index": index, "status": status})
+			return err
+		}
+
+		var aliased string
+		if inUse[index] {
+			aliased = *alias
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\n", index, count, aliased)
+	}
+
+	return w.Flush()
+}
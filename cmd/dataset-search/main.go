@@ -2,17 +2,19 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"io/ioutil"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/ONSdigital/dp-census-dataset-search-api/api"
 	"github.com/ONSdigital/dp-census-dataset-search-api/config"
 	es "github.com/ONSdigital/dp-census-dataset-search-api/internal/elasticsearch"
-	"github.com/ONSdigital/dp-census-dataset-search-api/models"
-	dphttp "github.com/ONSdigital/dp-net/http"
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/metadata"
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/metrics"
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/taxonomy"
+	"github.com/ONSdigital/go-ns/server"
 	"github.com/ONSdigital/log.go/log"
 )
 
@@ -20,7 +22,16 @@ func main() {
 	log.Namespace = "dp-search-api"
 	ctx := context.Background()
 
-	if err := run(ctx); err != nil {
+	if err := run(ctx, os.Args[1:]); err != nil {
+		if err == errUsage {
+			os.Exit(2)
+		}
+
+		// the diff has already been printed
+		if err == taxonomy.ErrChanged {
+			os.Exit(1)
+		}
+
 		log.Event(ctx, "application unexpectedly failed", log.ERROR, log.Error(err))
 		os.Exit(1)
 	}
@@ -28,67 +39,188 @@ func main() {
 	os.Exit(0)
 }
 
-func run(ctx context.Context) error {
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
-
+func run(ctx context.Context, args []string) error {
 	cfg, err := config.Get()
 	if err != nil {
 		log.Event(ctx, "failed to retrieve configuration", log.FATAL, log.Error(err))
 		return err
 	}
 
+	// running without a command serves the api, as the binary did before it had commands
+	if len(args) == 0 {
+		return serve(ctx, cfg, args)
+	}
+
+	return rootCommand.execute(ctx, cfg, rootCommand.name, args)
+}
+
+var serveCommand = &command{
+	name:        "serve",
+	description: "serve the dataset search api, the default when no command is given",
+	run:         serve,
+}
+
+func serve(ctx context.Context, cfg *config.Config, args []string) error {
+	if err := parseFlags(newFlagSet("dataset-search serve", ""), args, 0, 0); err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
 	log.Event(ctx, "config on startup", log.INFO, log.Data{"config": cfg})
 
-	// Read in Taxonomy JSON into memory
-	taxonomyFile, err := ioutil.ReadFile(cfg.TaxonomyFilename)
+	registry := metrics.NewRegistry()
+
+	esAPI, err := es.NewAPIFromConfig(ctx, cfg)
 	if err != nil {
-		log.Event(ctx, "failed to read taxonomy file", log.ERROR, log.Error(err), log.Data{"taxonomy_filename": cfg.TaxonomyFilename})
 		return err
 	}
 
-	var taxonomy models.Taxonomy
-
-	if err = json.Unmarshal([]byte(taxonomyFile), &taxonomy); err != nil {
-		log.Event(ctx, "unable to unmarshal taxonomy into struct", log.ERROR, log.Error(err), log.Data{"taxonomy_filename": cfg.TaxonomyFilename})
+	if err = esAPI.RegisterMetrics(registry); err != nil {
+		log.Event(ctx, "failed to register elasticsearch metrics", log.ERROR, log.Error(err))
 		return err
 	}
 
-	// Read in Dimensions JSON into memory
-	dimensionsFile, err := ioutil.ReadFile(cfg.DimensionsFilename)
+	_, status, err := esAPI.CallElastic(ctx, "/", "GET", nil)
 	if err != nil {
-		log.Event(ctx, "failed to read dimensions file", log.ERROR, log.Error(err), log.Data{"dimensions_filename": cfg.DimensionsFilename})
+		log.Event(ctx, "failed to start up, unable to connect to elastic search instance", log.ERROR, log.Error(err), log.Data{"http_status": status})
 		return err
 	}
 
-	var dimensions models.DimensionsDoc
+	// the client is closed last, as deferred calls run in reverse, so the background
+	// loops deferred below have stopped using it by then
+	esAPI.StartNodeCheck(ctx, cfg.ElasticSearchNodeCheck)
+	defer esAPI.Close()
 
-	if err = json.Unmarshal([]byte(dimensionsFile), &dimensions); err != nil {
-		log.Event(ctx, "unable to unmarshal taxonomy into struct", log.ERROR, log.Error(err), log.Data{"taxonomy_filename": cfg.TaxonomyFilename})
+	// Read in Taxonomy into memory from metadata index, or JSON file if unavailable
+	taxonomy, taxonomyFromFile, err := metadata.LoadTaxonomy(ctx, esAPI, cfg.MetadataIndex, cfg.TaxonomyFilename)
+	if err != nil {
 		return err
 	}
 
-	cli := dphttp.NewClient()
-	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+	// Read in Dimensions into memory from metadata index, or JSON file if unavailable
+	dimensions, dimensionsFromFile, err := metadata.LoadDimensions(ctx, esAPI, cfg.MetadataIndex, cfg.DimensionsFilename)
+	if err != nil {
+		return err
+	}
 
-	_, status, err := esAPI.CallElastic(ctx, cfg.ElasticSearchAPIURL, "GET", nil)
+	searchAPI, err := api.New(
+		api.WithElasticsearch(esAPI),
+		api.WithDatasetIndex(cfg.DatasetIndex),
+		api.WithDimensions(*dimensions),
+		api.WithTaxonomy(*taxonomy),
+		api.WithMaxOffset(cfg.MaxSearchResultsOffset),
+		api.WithMetrics(registry),
+	)
 	if err != nil {
-		log.Event(ctx, "failed to start up, unable to connect to elastic search instance", log.ERROR, log.Error(err), log.Data{"http_status": status})
+		log.Event(ctx, "failed to create search api", log.ERROR, log.Error(err))
 		return err
 	}
 
+	router := http.NewServeMux()
+	router.Handle("/metrics", metrics.Handler(registry))
+	router.Handle("/", searchAPI)
+
+	httpServer := server.New(cfg.BindAddr, router)
+
+	// Disable this here to allow service to manage graceful shutdown of the entire app.
+	httpServer.HandleOSSignals = false
+
+	// a search still retrying when the write timeout passes never reaches the client
+	if cfg.ElasticSearchTotalTimeout <= 0 || cfg.ElasticSearchTotalTimeout >= httpServer.WriteTimeout {
+		log.Event(ctx, "elasticsearch total timeout should be set below the http write timeout", log.WARN, log.Data{"total_timeout": cfg.ElasticSearchTotalTimeout.String(), "write_timeout": httpServer.WriteTimeout.String()})
+	}
+
 	apiErrors := make(chan error, 1)
 
-	api.CreateAndInitialiseSearchAPI(ctx, cfg.BindAddr, esAPI, cfg.MaxSearchResultsOffset, cfg.DatasetIndex, dimensions, taxonomy, apiErrors)
+	go func() {
+		log.Event(ctx, "Starting api...", log.INFO)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Event(ctx, "api http server returned error", log.ERROR, log.Error(err))
+			apiErrors <- err
+		}
+	}()
+
+	searchAPI.StartHealthCheck(ctx, cfg.HealthCheckInterval)
+	defer searchAPI.StopHealthCheck()
+
+	// Reload a file when it changes or on SIGHUP if its document was read from the file,
+	// because there is no metadata index or the document could not be read from it on startup
+	var watcher *metadata.Watcher
+	if taxonomyFromFile || dimensionsFromFile {
+		watcher = &metadata.Watcher{
+			Interval:     cfg.FileWatchInterval,
+			OnDimensions: searchAPI.SetDimensions,
+			OnTaxonomy:   searchAPI.SetTaxonomy,
+		}
+		if taxonomyFromFile {
+			watcher.TaxonomyFilename = cfg.TaxonomyFilename
+		}
+		if dimensionsFromFile {
+			watcher.DimensionsFilename = cfg.DimensionsFilename
+		}
+	}
+
+	if cfg.MetadataIndex != "" {
+		// Refresh taxonomy and dimensions from metadata index, the refresher runs the watcher
+		// until each document that fell back to its file can be read from the index
+		refresher := &metadata.Refresher{
+			Elasticsearch: esAPI,
+			IndexName:     cfg.MetadataIndex,
+			Interval:      cfg.MetadataRefreshInterval,
+			OnDimensions:  searchAPI.SetDimensions,
+			OnTaxonomy:    searchAPI.SetTaxonomy,
+			Watcher:       watcher,
+		}
+		refresher.Start(ctx)
+		defer refresher.Stop()
+	} else {
+		watcher.Start(ctx)
+		defer watcher.Stop()
+	}
 
 	// block until a fatal error occurs
 	select {
 	case err := <-apiErrors:
 		log.Event(ctx, "api error received", log.ERROR, log.Error(err))
 		return err
-	case <-signals:
-		log.Event(ctx, "os signal received", log.INFO)
+	case sig := <-signals:
+		log.Event(ctx, "os signal received", log.INFO, log.Data{"signal": sig.String()})
+	}
+
+	return shutdown(ctx, cfg.GracefulShutdownTimeout, cfg.ShutdownHealthDelay, httpServer, searchAPI)
+}
+
+// shutdown fails the health endpoint and, after the delay so the orchestrator sees the
+// failing health check and stops routing requests, stops accepting connections and
+// drains in-flight requests. The delay counts towards the timeout, an error is returned
+// if requests were still running when the timeout expired
+func shutdown(ctx context.Context, timeout, healthDelay time.Duration, httpServer *server.Server, searchAPI *api.SearchAPI) error {
+	log.Event(ctx, "starting graceful shutdown", log.INFO, log.Data{"timeout": timeout.String(), "health_delay": healthDelay.String()})
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	searchAPI.SetShuttingDown()
+	searchAPI.StopHealthCheck()
+
+	select {
+	case <-time.After(healthDelay):
+	case <-shutdownCtx.Done():
+	}
+
+	err := httpServer.Shutdown(shutdownCtx)
+	if err == nil {
+		log.Event(ctx, "graceful shutdown of http server complete", log.INFO)
+	}
+
+	if err != nil {
+		log.Event(ctx, "failed to drain in-flight requests before shutdown timeout", log.ERROR, log.Error(err))
+		return err
 	}
 
+	log.Event(ctx, "graceful shutdown complete", log.INFO)
+
 	return nil
 }
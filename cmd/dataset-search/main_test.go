@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/api"
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/ONSdigital/go-ns/server"
+)
+
+// elasticsearchStub stands in for a healthy elasticsearch holding one dataset
+type elasticsearchStub struct{}
+
+func (elasticsearchStub) CountDocuments(ctx context.Context, indexName string) (int, int, error) {
+	return 1, http.StatusOK, nil
+}
+
+func (elasticsearchStub) GetClusterHealth(ctx context.Context) (*models.ClusterHealth, int, error) {
+	return &models.ClusterHealth{Status: "green"}, http.StatusOK, nil
+}
+
+func (elasticsearchStub) QueryDatasetSearch(ctx context.Context, indexName string, query interface{}, limit, offset int) (*models.SearchResponse, int, error) {
+	return &models.SearchResponse{}, http.StatusOK, nil
+}
+
+// startServer serves the search api on a free port, along with a route that takes the
+// duration to respond, returning the address of the server
+func startServer(t *testing.T, duration time.Duration) (*server.Server, *api.SearchAPI, string) {
+	searchAPI, err := api.New(api.WithElasticsearch(elasticsearchStub{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	searchAPI.StartHealthCheck(context.Background(), time.Hour)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	router := http.NewServeMux()
+	router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(duration)
+	})
+	router.Handle("/", searchAPI)
+
+	httpServer := server.New(addr, router)
+	httpServer.HandleOSSignals = false
+
+	go httpServer.ListenAndServe()
+
+	// wait for the server to accept connections
+	for i := 0; ; i++ {
+		resp, err := http.Get("http://" + addr + "/health")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 50 {
+			t.Fatalf("server not started: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return httpServer, searchAPI, addr
+}
+
+func TestShutdown(t *testing.T) {
+	ctx := context.Background()
+	httpServer, searchAPI, addr := startServer(t, 300*time.Millisecond)
+
+	// a request in flight when shutdown starts
+	slow := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("slow request code = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+		}
+		slow <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- shutdown(ctx, 2*time.Second, 200*time.Millisecond, httpServer, searchAPI)
+	}()
+
+	// the listener stays open through the delay, with the health endpoint failing
+	time.Sleep(50 * time.Millisecond)
+	resp, err := http.Get("http://" + addr + "/health")
+	if err != nil {
+		t.Fatalf("health check during the delay failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("health code = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	if err = <-slow; err != nil {
+		t.Errorf("in-flight request failed: %v", err)
+	}
+	if err = <-done; err != nil {
+		t.Errorf("shutdown err = %v, want nil", err)
+	}
+
+	if resp, err = http.Get("http://" + addr + "/health"); err == nil {
+		resp.Body.Close()
+		t.Error("server still accepting connections after shutdown")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	httpServer, searchAPI, addr := startServer(t, time.Second)
+
+	go func() {
+		if resp, err := http.Get("http://" + addr + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the delay counts towards the timeout, leaving too little time to drain the request
+	start := time.Now()
+	err := shutdown(context.Background(), 300*time.Millisecond, 200*time.Millisecond, httpServer, searchAPI)
+	if err == nil {
+		t.Error("expected an error shutting down with a request still running")
+	}
+	if elapsed := time.Since(start); elapsed > 800*time.Millisecond {
+		t.Errorf("shutdown took %s, want it bounded by the timeout", elapsed)
+	}
+
+	httpServer.Close(context.Background())
+}
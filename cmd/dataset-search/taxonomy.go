@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/config"
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/taxonomy"
+)
+
+var taxonomyCommand = &command{
+	name:        "taxonomy",
+	description: "fetch the taxonomy from the ons website, or diff it against the taxonomy file",
+	subcommands: []*command{
+		{
+			name:        "fetch",
+			description: "crawl the taxonomy of the ons website and write it to the taxonomy file",
+			run:         fetchTaxonomy,
+		},
+		{
+			name:        "diff",
+			description: "crawl the taxonomy of the ons website and print how it differs from the taxonomy file",
+			run:         diffTaxonomy,
+		},
+	},
+}
+
+func fetchTaxonomy(ctx context.Context, cfg *config.Config, args []string) error {
+	opts := taxonomy.DefaultOptions()
+	opts.Filename = cfg.TaxonomyFilename
+
+	fs := newFlagSet("dataset-search taxonomy fetch", "")
+	opts.Register(fs)
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	return taxonomy.Run(ctx, opts)
+}
+
+func diffTaxonomy(ctx context.Context, cfg *config.Config, args []string) error {
+	opts := taxonomy.DefaultOptions()
+	opts.Filename = cfg.TaxonomyFilename
+
+	fs := newFlagSet("dataset-search taxonomy diff", "")
+	opts.Register(fs)
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	// diff never writes the taxonomy file, whatever the flags
+	opts.Check = true
+
+	return taxonomy.Run(ctx, opts)
+}
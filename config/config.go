@@ -1,20 +1,53 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
 // Config is the filing resource handler config
 type Config struct {
-	BindAddr                  string `envconfig:"BIND_ADDR"                  json:"-"`
-	DatasetIndex              string `envconfig:"DATASET_SEARCH_INDEX"`
-	DimensionsFilename        string `envconfig:"DIMENSIONS_FILENAME"`
-	ElasticSearchAPIURL       string `envconfig:"ELASTIC_SEARCH_URL"         json:"-"`
-	MaxSearchResultsOffset    int    `envconfig:"MAX_SEARCH_RESULTS_OFFSET"`
-	SignElasticsearchRequests bool   `envconfig:"SIGN_ELASTICSEARCH_REQUESTS"`
-	TaxonomyFilename          string `envconfig:"TAXONOMY_FILENAME"`
+	AWSRegion                   string        `envconfig:"AWS_REGION"`
+	AWSService                  string        `envconfig:"AWS_SERVICE"`
+	BindAddr                    string        `envconfig:"BIND_ADDR"                       json:"-"`
+	CircuitBreakerCooldown      time.Duration `envconfig:"CIRCUIT_BREAKER_COOLDOWN"`
+	CircuitBreakerThreshold     int           `envconfig:"CIRCUIT_BREAKER_THRESHOLD"`
+	DatasetIndex                string        `envconfig:"DATASET_SEARCH_INDEX"`
+	DimensionsFilename          string        `envconfig:"DIMENSIONS_FILENAME"`
+	ElasticSearchAPIKey         string        `envconfig:"ELASTIC_SEARCH_API_KEY"          json:"-"`
+	ElasticSearchAPIURLs        []string      `envconfig:"ELASTIC_SEARCH_URL"              json:"-"`
+	ElasticSearchCACertFile     string        `envconfig:"ELASTIC_SEARCH_CA_CERT_FILE"`
+	ElasticSearchClientCertFile string        `envconfig:"ELASTIC_SEARCH_CLIENT_CERT_FILE"`
+	ElasticSearchClientKeyFile  string        `envconfig:"ELASTIC_SEARCH_CLIENT_KEY_FILE"  json:"-"`
+	ElasticSearchGetTimeout     time.Duration `envconfig:"ELASTIC_SEARCH_GET_TIMEOUT"`
+	ElasticSearchMaxRetries     int           `envconfig:"ELASTIC_SEARCH_MAX_RETRIES"`
+	ElasticSearchNodeCheck      time.Duration `envconfig:"ELASTIC_SEARCH_NODE_CHECK_INTERVAL"`
+	ElasticSearchPassword       string        `envconfig:"ELASTIC_SEARCH_PASSWORD"         json:"-"`
+	ElasticSearchRetryBackoff   time.Duration `envconfig:"ELASTIC_SEARCH_RETRY_BACKOFF"`
+	ElasticSearchSearchTimeout  time.Duration `envconfig:"ELASTIC_SEARCH_SEARCH_TIMEOUT"`
+	ElasticSearchTotalTimeout   time.Duration `envconfig:"ELASTIC_SEARCH_TOTAL_TIMEOUT"`
+	ElasticSearchUsername       string        `envconfig:"ELASTIC_SEARCH_USERNAME"`
+	ElasticSearchWriteTimeout   time.Duration `envconfig:"ELASTIC_SEARCH_WRITE_TIMEOUT"`
+	FileWatchInterval           time.Duration `envconfig:"FILE_WATCH_INTERVAL"`
+	GracefulShutdownTimeout     time.Duration `envconfig:"GRACEFUL_SHUTDOWN_TIMEOUT"`
+	HealthCheckInterval         time.Duration `envconfig:"HEALTH_CHECK_INTERVAL"`
+	MaxSearchResultsOffset      int           `envconfig:"MAX_SEARCH_RESULTS_OFFSET"`
+	MetadataIndex               string        `envconfig:"METADATA_INDEX"`
+	MetadataRefreshInterval     time.Duration `envconfig:"METADATA_REFRESH_INTERVAL"`
+	ShutdownHealthDelay         time.Duration `envconfig:"SHUTDOWN_HEALTH_DELAY"`
+	SignElasticsearchRequests   bool          `envconfig:"SIGN_ELASTICSEARCH_REQUESTS"`
+	TaxonomyFilename            string        `envconfig:"TAXONOMY_FILENAME"`
 }
 
+// A list of the defaults shared with the search api and the upload script, for when
+// they are used without the configuration
+const (
+	DefaultDatasetIndex           = "dataset-test"
+	DefaultMaxSearchResultsOffset = 1000
+	DefaultMetadataIndex          = "dataset-metadata"
+)
+
 var cfg *Config
 
 // Get configures the application and returns the configuration
@@ -24,13 +57,30 @@ func Get() (*Config, error) {
 	}
 
 	cfg = &Config{
-		BindAddr:                  ":10200",
-		DatasetIndex:              "dataset-test",
-		DimensionsFilename:        "data/dimensions.json",
-		ElasticSearchAPIURL:       "http://localhost:9200",
-		MaxSearchResultsOffset:    1000,
-		SignElasticsearchRequests: false,
-		TaxonomyFilename:          "data/taxonomy.json",
+		AWSRegion:                  "eu-west-1",
+		AWSService:                 "es",
+		BindAddr:                   ":10200",
+		CircuitBreakerCooldown:     30 * time.Second,
+		CircuitBreakerThreshold:    5,
+		DatasetIndex:               DefaultDatasetIndex,
+		DimensionsFilename:         "data/dimensions.json",
+		ElasticSearchAPIURLs:       []string{"http://localhost:9200"},
+		ElasticSearchGetTimeout:    10 * time.Second,
+		ElasticSearchMaxRetries:    3,
+		ElasticSearchNodeCheck:     10 * time.Second,
+		ElasticSearchRetryBackoff:  100 * time.Millisecond,
+		ElasticSearchSearchTimeout: 10 * time.Second,
+		ElasticSearchTotalTimeout:  8 * time.Second,
+		ElasticSearchWriteTimeout:  60 * time.Second,
+		FileWatchInterval:          10 * time.Second,
+		GracefulShutdownTimeout:    20 * time.Second,
+		HealthCheckInterval:        30 * time.Second,
+		MaxSearchResultsOffset:     DefaultMaxSearchResultsOffset,
+		MetadataIndex:              DefaultMetadataIndex,
+		MetadataRefreshInterval:    5 * time.Minute,
+		ShutdownHealthDelay:        5 * time.Second,
+		SignElasticsearchRequests:  false,
+		TaxonomyFilename:           "data/taxonomy.json",
 	}
 
 	return cfg, envconfig.Process("", cfg)
@@ -0,0 +1,423 @@
+package cmddatasets
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/datasetcsv"
+	"github.com/ONSdigital/log.go/log"
+	"github.com/globalsign/mgo"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	collection = "datasets"
+	database   = "datasets"
+
+	onsSite = "https://www.ons.gov.uk"
+
+	defaultBindAddr = "localhost:27017"
+	defaultFilename = "cmd-datasets.csv"
+
+	sourceDatasetAPI = "dataset-api"
+	sourceMongo      = "mongo"
+)
+
+// Options holds the settings used to fetch datasets, each of which can be set by a
+// command line flag
+type Options struct {
+	BrokenLinksFilename string
+	DatasetAPIURL       string
+	Filename            string
+	LinkRate            int
+	LinkRetries         int
+	LinkTimeout         time.Duration
+	LinkWorkers         int
+	MongoBindAddr       string
+	ServiceAuthToken    string
+	Source              string
+}
+
+// DefaultOptions returns the options of a fetch run from the scripts directory
+func DefaultOptions() Options {
+	return Options{
+		DatasetAPIURL:    defaultDatasetAPIURL,
+		Filename:         defaultFilename,
+		LinkRate:         defaultLinkRate,
+		LinkRetries:      defaultLinkRetries,
+		LinkTimeout:      defaultLinkTimeout,
+		LinkWorkers:      defaultLinkWorkers,
+		MongoBindAddr:    defaultBindAddr,
+		ServiceAuthToken: os.Getenv("SERVICE_AUTH_TOKEN"),
+		Source:           sourceMongo,
+	}
+}
+
+// Register adds the fetch flags to the flag set, each flag defaults to the value already
+// set in the options
+func (o *Options) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.MongoBindAddr, "mongodb-bind-addr", o.MongoBindAddr, "the address including authorisation if needed to bind to mongo database")
+	fs.StringVar(&o.Filename, "filename", o.Filename, "the name and path of the file location to create file")
+	fs.StringVar(&o.Source, "source", o.Source, "where datasets are retrieved from, one of mongo or dataset-api")
+	fs.StringVar(&o.DatasetAPIURL, "dataset-api-url", o.DatasetAPIURL, "the url of the dataset api, used when the source is dataset-api")
+	fs.StringVar(&o.ServiceAuthToken, "service-auth-token", o.ServiceAuthToken, "the service auth token sent to the dataset api, leave empty to retrieve published datasets only")
+	fs.IntVar(&o.LinkWorkers, "link-workers", o.LinkWorkers, "the number of links on the ons website checked at the same time")
+	fs.IntVar(&o.LinkRate, "link-rate", o.LinkRate, "the maximum number of requests sent to the ons website each second")
+	fs.IntVar(&o.LinkRetries, "link-retries", o.LinkRetries, "the number of times a link check is retried after a timeout, rate limit or server error")
+	fs.DurationVar(&o.LinkTimeout, "link-timeout", o.LinkTimeout, "the time allowed for each request to the ons website")
+	fs.StringVar(&o.BrokenLinksFilename, "broken-links-filename", o.BrokenLinksFilename, "the json file the datasets with broken links are written to, defaults to only logging them")
+}
+
+// datasetSource retrieves datasets and the versions of their editions
+type datasetSource interface {
+	getDatasets(ctx context.Context) ([]DatasetUpdate, error)
+	getDatasetInstance(ctx context.Context, datasetID, edition, version string) (*Version, error)
+}
+
+// Run retrieves the cmd datasets from the source of the options and writes each dataset
+// with a link on the ons website to a csv file
+func Run(ctx context.Context, opts Options) error {
+	if opts.MongoBindAddr == "" {
+		opts.MongoBindAddr = defaultBindAddr
+	}
+
+	if opts.Filename == "" {
+		opts.Filename = defaultFilename
+	}
+
+	if opts.Source == "" {
+		opts.Source = sourceMongo
+	}
+
+	if opts.DatasetAPIURL == "" {
+		opts.DatasetAPIURL = defaultDatasetAPIURL
+	}
+
+	if opts.LinkWorkers < 1 {
+		opts.LinkWorkers = defaultLinkWorkers
+	}
+
+	if opts.LinkRate < 1 {
+		opts.LinkRate = defaultLinkRate
+	}
+
+	if opts.LinkRetries < 0 {
+		opts.LinkRetries = 0
+	}
+
+	if opts.LinkTimeout <= 0 {
+		opts.LinkTimeout = defaultLinkTimeout
+	}
+
+	filename, source := opts.Filename, opts.Source
+
+	log.Event(ctx, "fetch options", log.INFO, log.Data{"source": source, "mongodb_bind_addr": opts.MongoBindAddr, "dataset_api_url": opts.DatasetAPIURL, "link_workers": opts.LinkWorkers, "link_rate": opts.LinkRate, "link_retries": opts.LinkRetries, "link_timeout": opts.LinkTimeout.String()})
+
+	var datasetSource datasetSource
+	switch source {
+	case sourceMongo:
+		mongo := &Mongo{
+			Collection: collection,
+			Database:   database,
+			URI:        opts.MongoBindAddr,
+		}
+
+		session, err := mongo.Init()
+		if err != nil {
+			log.Event(ctx, "unable to connect to mongo database", log.ERROR, log.Error(err), log.Data{"mongodb-bind-addr": opts.MongoBindAddr})
+			return err
+		}
+		defer session.Close()
+
+		mongo.Session = session
+		datasetSource = mongo
+	case sourceDatasetAPI:
+		datasetSource = NewDatasetAPI(opts.DatasetAPIURL, opts.ServiceAuthToken)
+	default:
+		err := errors.New("invalid source, expected one of mongo or dataset-api")
+		log.Event(ctx, "invalid source", log.ERROR, log.Error(err), log.Data{"source": source})
+		return err
+	}
+
+	datasets, err := datasetSource.getDatasets(ctx)
+	if err != nil {
+		log.Event(ctx, "unable to retrieve list of datasets", log.ERROR, log.Error(err), log.Data{"source": source})
+		return err
+	}
+
+	var links []datasetLink
+	for i, dataset := range datasets {
+		// Check data exists on ons website as we are building links to that website
+		if dataset.Current != nil {
+			if dataset.Current.Links != nil {
+				if dataset.Current.Links.LatestVersion != nil {
+					if dataset.Current.Links.LatestVersion.HRef != "" {
+						href := dataset.Current.Links.LatestVersion.HRef
+						splitHReF := strings.SplitAfter(href, "datasets")
+						endOfPath := strings.SplitAfter(splitHReF[1], "versions")
+						fullPath := "/datasets" + endOfPath[0]
+
+						// Get edition
+						getEdition := strings.SplitAfter(endOfPath[0], "editions/")
+						edition := strings.TrimSuffix(getEdition[1], "/versions")
+						log.Event(context.Background(), "found dataset", log.INFO, log.Data{"index": i})
+
+						links = append(links, datasetLink{
+							Dataset: dataset,
+							Edition: edition,
+							URL:     onsSite + fullPath,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	urls := make([]string, len(links))
+	for i, link := range links {
+		urls[i] = link.URL
+	}
+
+	checker := newLinkChecker(opts.LinkWorkers, opts.LinkRate, opts.LinkRetries, opts.LinkTimeout)
+	results := checker.checkLinks(ctx, urls)
+
+	var brokenLinks []brokenLink
+	for i, result := range results {
+		if result.ok() {
+			continue
+		}
+
+		brokenLinks = append(brokenLinks, brokenLink{
+			DatasetID:  links[i].Dataset.ID,
+			Title:      links[i].Dataset.Current.Title,
+			linkResult: result,
+		})
+		log.Event(ctx, "dataset link not found on ons website, skipping", log.WARN, log.Data{"dataset_id": links[i].Dataset.ID, "url": result.URL, "status": result.Status, "reason": result.Reason, "attempts": result.Attempts})
+	}
+
+	log.Event(ctx, "checked dataset links", log.INFO, log.Data{"checked": len(results), "broken": len(brokenLinks)})
+
+	if opts.BrokenLinksFilename != "" {
+		if err := writeBrokenLinks(opts.BrokenLinksFilename, brokenLinks); err != nil {
+			log.Event(ctx, "failed to write broken links file", log.ERROR, log.Error(err), log.Data{"broken_links_filename": opts.BrokenLinksFilename})
+			return err
+		}
+	}
+
+	// the file is only replaced once every dataset has been written
+	writer, err := datasetcsv.Create(filename)
+	if err != nil {
+		log.Event(ctx, "failed to create file", log.ERROR, log.Error(err), log.Data{"filename": filename})
+		return err
+	}
+
+	// rows are written in the order of the datasets, whatever order the links were checked in
+	for i, link := range links {
+		if !results[i].ok() {
+			continue
+		}
+
+		dataset, url, edition := link.Dataset, link.URL, link.Edition
+
+		var topic string
+		if dataset.Current.QMI != nil && dataset.Current.QMI.HRef != "" {
+
+			// Remove host from qmi, leaving the path to methodology only
+			qmiPath := strings.SplitAfter(dataset.Current.QMI.HRef, "https://www.ons.gov.uk/")
+
+			// Check length to determine if qmi is an ons.gov.uk url
+			if len(qmiPath) > 1 {
+				// Split path in two leaving the dataset name separate from taxonomy of topics
+				qmiArray := strings.SplitAfter(qmiPath[1], "methodologies")
+				// Create a list of topics
+				list := strings.SplitAfter(qmiArray[0], "/")
+				// Find lowest level topic in list, this will be the second from last value due to "methodologies" keyword being the last value in list
+				topic = list[len(list)-2]
+
+				// Remove trailing whitespace off topic
+				topic = strings.TrimRight(topic, "/")
+			}
+		}
+
+		// Retrieve dataset dimensions by finding latest published instance of dataset
+		latestVersion := dataset.Current.Links.LatestVersion.ID
+
+		// Get latest dataset instance
+		instance, err := datasetSource.getDatasetInstance(ctx, dataset.ID, edition, latestVersion)
+		if err != nil {
+			log.Event(ctx, "unable to retrieve dataset instance", log.ERROR, log.Error(err), log.Data{"source": source, "dataset_id": dataset.ID, "edition": edition, "version": latestVersion})
+			writer.Abort()
+			return err
+		}
+
+		row := datasetcsv.Row{
+			Alias:       dataset.ID,
+			Description: dataset.Current.Description,
+			Link:        url,
+			Title:       dataset.Current.Title,
+			Topic:       topic,
+		}
+
+		for _, dimension := range instance.Dimensions {
+			label := dimension.Label
+			if label == "" {
+				label = dimension.Name
+			}
+
+			row.DimensionNames = append(row.DimensionNames, dimension.Name)
+			row.DimensionLabels = append(row.DimensionLabels, label)
+		}
+
+		err = writer.Write(row)
+		if errors.Is(err, datasetcsv.ErrDimensionSeparator) {
+			log.Event(ctx, "skipping dataset with a dimension that cannot be written to file", log.WARN, log.Error(err), log.Data{"dataset_id": dataset.ID})
+			continue
+		}
+		if err != nil {
+			log.Event(ctx, "failed to write dataset to file", log.ERROR, log.Error(err), log.Data{"dataset_id": dataset.ID, "filename": filename})
+			writer.Abort()
+			return err
+		}
+	}
+
+	if err = writer.Commit(); err != nil {
+		log.Event(ctx, "failed to write file", log.ERROR, log.Error(err), log.Data{"filename": filename})
+		return err
+	}
+
+	log.Event(ctx, "successfully written datasets to file", log.INFO, log.Data{"filename": filename})
+
+	return nil
+}
+
+// datasetLink is a dataset along with its link on the ons website and the edition of its
+// latest version
+type datasetLink struct {
+	Dataset DatasetUpdate
+	Edition string
+	URL     string
+}
+
+// Mongo represents a simplistic MongoDB configuration.
+type Mongo struct {
+	Collection string
+	Database   string
+	Session    *mgo.Session
+	URI        string
+}
+
+// Init creates a new mgo.Session with a strong consistency and a write mode of "majortiy".
+func (m *Mongo) Init() (session *mgo.Session, err error) {
+	if session != nil {
+		return nil, errors.New("session already exists")
+	}
+
+	if session, err = mgo.Dial(m.URI); err != nil {
+		return nil, err
+	}
+
+	session.EnsureSafe(&mgo.Safe{WMode: "majority"})
+	session.SetMode(mgo.Strong, true)
+
+	return session, nil
+}
+
+// DatasetUpdate represents an evolving dataset with the current dataset and the updated dataset
+type DatasetUpdate struct {
+	ID      string   `bson:"_id,omitempty"         json:"id,omitempty"`
+	Current *Dataset `bson:"current,omitempty"     json:"current,omitempty"`
+}
+
+// Dataset represents information related to a single dataset
+type Dataset struct {
+	Description string        `bson:"description,omitempty" json:"description,omitempty"`
+	Links       *DatasetLinks `bson:"links,omitempty"       json:"links,omitempty"`
+	QMI         *QMIObject    `bson:"qmi,omitempty"       json:"qmi,omitempty"`
+	Title       string        `bson:"title,omitempty"       json:"title,omitempty"`
+}
+
+// DatasetLinks represents a list of specific links related to the dataset resource
+type DatasetLinks struct {
+	LatestVersion *LinkObject `bson:"latest_version,omitempty"  json:"latest_version,omitempty"`
+}
+
+// LinkObject represents a generic structure for all links
+type LinkObject struct {
+	HRef string `bson:"href,omitempty"  json:"href,omitempty"`
+	ID   string `bson:"id,omitempty"  json:"id,omitempty"`
+}
+
+type QMIObject struct {
+	HRef string `bson:"href,omitempty"  json:"href,omitempty"`
+}
+
+// Version represents information related to a single version for an edition of a dataset
+type Version struct {
+	Dimensions []Dimension `bson:"dimensions,omitempty"     json:"dimensions,omitempty"`
+	Edition    string      `bson:"edition,omitempty"        json:"edition,omitempty"`
+	ID         string      `bson:"id,omitempty"             json:"id,omitempty"`
+	Version    int         `bson:"version,omitempty"        json:"version,omitempty"`
+}
+
+// Dimension represents an overview for a single dimension. This includes a link to the code list API
+// which provides metadata about the dimension and all possible values.
+type Dimension struct {
+	Label string `bson:"label,omitempty"         json:"label,omitempty"`
+	Name  string `bson:"name,omitempty"          json:"name,omitempty"`
+}
+
+// getDatasets retrieves all dataset documents
+func (m *Mongo) getDatasets(ctx context.Context) ([]DatasetUpdate, error) {
+	s := m.Session.Copy()
+	defer s.Close()
+
+	iter := s.DB(m.Database).C(m.Collection).Find(nil).Iter()
+	defer func() {
+		err := iter.Close()
+		if err != nil {
+			log.Event(ctx, "error closing iterator", log.ERROR, log.Error(err))
+		}
+	}()
+
+	datasets := []DatasetUpdate{}
+	if err := iter.All(&datasets); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, errors.New("dataset not found")
+		}
+		return nil, err
+	}
+
+	return datasets, nil
+}
+
+// getDatasetsInstance retrieves a single instance of a dataset
+func (m *Mongo) getDatasetInstance(ctx context.Context, datasetID, edition, version string) (*Version, error) {
+	s := m.Session.Copy()
+	defer s.Close()
+
+	versionNumber, err := strconv.Atoi(version)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := bson.M{
+		"links.dataset.id": datasetID,
+		"edition":          edition,
+		"version":          versionNumber,
+	}
+
+	var datasetVersion Version
+	err = s.DB(m.Database).C("instances").Find(selector).One(&datasetVersion)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, errors.New("instance not found")
+		}
+		return nil, err
+	}
+	return &datasetVersion, nil
+}
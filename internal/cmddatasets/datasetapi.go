@@ -0,0 +1,150 @@
+package cmddatasets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/log.go/log"
+)
+
+const (
+	defaultDatasetAPIURL = "http://localhost:22000"
+	defaultPageSize      = 100
+	requestTimeout       = 30 * time.Second
+)
+
+// DatasetAPI retrieves datasets and their versions from the dataset API over http
+type DatasetAPI struct {
+	Client           *http.Client
+	PageSize         int
+	ServiceAuthToken string
+	URL              string
+}
+
+// NewDatasetAPI creates a DatasetAPI for the dataset API at the url, the service auth
+// token can be empty to only retrieve published datasets
+func NewDatasetAPI(datasetAPIURL, serviceAuthToken string) *DatasetAPI {
+	return &DatasetAPI{
+		Client:           &http.Client{Timeout: requestTimeout},
+		PageSize:         defaultPageSize,
+		ServiceAuthToken: serviceAuthToken,
+		URL:              strings.TrimRight(datasetAPIURL, "/"),
+	}
+}
+
+// datasetsPage represents a page of the list of datasets returned by the dataset API
+type datasetsPage struct {
+	Count      int           `json:"count"`
+	Items      []datasetItem `json:"items"`
+	Limit      int           `json:"limit"`
+	Offset     int           `json:"offset"`
+	TotalCount int           `json:"total_count"`
+}
+
+// datasetItem represents a dataset in the list of datasets, requests with a service auth
+// token are returned the current and next dataset while other requests are returned the
+// current dataset on its own
+type datasetItem struct {
+	Dataset
+	ID      string   `json:"id"`
+	Current *Dataset `json:"current,omitempty"`
+}
+
+// getDatasets retrieves all datasets by paging through the list of datasets
+func (d *DatasetAPI) getDatasets(ctx context.Context) ([]DatasetUpdate, error) {
+	datasets := []DatasetUpdate{}
+
+	for offset := 0; ; {
+		query := url.Values{}
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(d.PageSize))
+
+		var page datasetsPage
+		if err := d.get(ctx, "/datasets?"+query.Encode(), &page); err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			current := item.Current
+			if current == nil {
+				dataset := item.Dataset
+				current = &dataset
+			}
+
+			datasets = append(datasets, DatasetUpdate{
+				ID:      item.ID,
+				Current: current,
+			})
+		}
+
+		offset += len(page.Items)
+
+		log.Event(ctx, "retrieved page of datasets", log.INFO, log.Data{"retrieved": offset, "total_count": page.TotalCount})
+
+		if len(page.Items) == 0 || offset >= page.TotalCount {
+			break
+		}
+	}
+
+	return datasets, nil
+}
+
+// getDatasetInstance retrieves a single version of an edition of a dataset
+func (d *DatasetAPI) getDatasetInstance(ctx context.Context, datasetID, edition, version string) (*Version, error) {
+	path := "/datasets/" + url.PathEscape(datasetID) + "/editions/" + url.PathEscape(edition) + "/versions/" + url.PathEscape(version)
+
+	var datasetVersion Version
+	if err := d.get(ctx, path, &datasetVersion); err != nil {
+		return nil, err
+	}
+
+	return &datasetVersion, nil
+}
+
+// get sends a request to the dataset API and decodes the json response into v
+func (d *DatasetAPI) get(ctx context.Context, path string, v interface{}) error {
+	logData := log.Data{"url": d.URL + path}
+
+	req, err := http.NewRequest(http.MethodGet, d.URL+path, nil)
+	if err != nil {
+		log.Event(ctx, "failed to create request for dataset api", log.ERROR, log.Error(err), logData)
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	if d.ServiceAuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+d.ServiceAuthToken)
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		log.Event(ctx, "failed to call dataset api", log.ERROR, log.Error(err), logData)
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return errors.New("not found in dataset api: " + path)
+	default:
+		err = fmt.Errorf("unexpected status code from dataset api: %d", resp.StatusCode)
+		logData["status"] = resp.StatusCode
+		log.Event(ctx, "request to dataset api failed", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		log.Event(ctx, "failed to decode dataset api response", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	return nil
+}
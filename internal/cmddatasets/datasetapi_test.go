@@ -0,0 +1,243 @@
+package cmddatasets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// datasetAPIStub stands in for the dataset API, serving the datasets a page at a time
+// and recording the requests it receives
+type datasetAPIStub struct {
+	// items are the json of each dataset in the list of datasets
+	items []string
+	// totalCount is returned instead of the number of items when set
+	totalCount int
+	// status is returned for every request when set
+	status int
+
+	mutex    sync.Mutex
+	offsets  []int
+	authHdrs []string
+}
+
+func (s *datasetAPIStub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mutex.Lock()
+	s.authHdrs = append(s.authHdrs, req.Header.Get("Authorization"))
+	s.mutex.Unlock()
+
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+
+	switch {
+	case req.URL.Path == "/datasets":
+		offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+
+		s.mutex.Lock()
+		s.offsets = append(s.offsets, offset)
+		s.mutex.Unlock()
+
+		end := offset + limit
+		if end > len(s.items) {
+			end = len(s.items)
+		}
+		var page []string
+		if offset < end {
+			page = s.items[offset:end]
+		}
+
+		totalCount := s.totalCount
+		if totalCount == 0 {
+			totalCount = len(s.items)
+		}
+
+		fmt.Fprintf(w, `{"count":%d,"offset":%d,"limit":%d,"total_count":%d,"items":[%s]}`, len(page), offset, limit, totalCount, strings.Join(page, ","))
+	case req.URL.Path == "/datasets/cpih01/editions/time-series/versions/3":
+		fmt.Fprint(w, `{"id":"v3","edition":"time-series","version":3,"dimensions":[{"name":"geography","label":"Geography"}]}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestDatasetAPI(stub *datasetAPIStub, serviceAuthToken string) (*DatasetAPI, func()) {
+	srv := httptest.NewServer(stub)
+
+	d := NewDatasetAPI(srv.URL+"/", serviceAuthToken)
+	d.PageSize = 2
+
+	return d, srv.Close
+}
+
+func publicItem(id string) string {
+	return fmt.Sprintf(`{"id":%q,"title":"Public %s","links":{"latest_version":{"id":"1"}}}`, id, id)
+}
+
+func privateItem(id string) string {
+	return fmt.Sprintf(`{"id":%q,"current":{"title":"Current %s"},"next":{"title":"Next %s"}}`, id, id, id)
+}
+
+func TestGetDatasetsPaging(t *testing.T) {
+	tests := []struct {
+		name        string
+		stub        *datasetAPIStub
+		wantIDs     []string
+		wantOffsets []int
+	}{
+		{
+			name:        "pages until the offset reaches the total count",
+			stub:        &datasetAPIStub{items: []string{publicItem("a"), publicItem("b"), publicItem("c")}},
+			wantIDs:     []string{"a", "b", "c"},
+			wantOffsets: []int{0, 2},
+		},
+		{
+			name:        "stops on a full last page",
+			stub:        &datasetAPIStub{items: []string{publicItem("a"), publicItem("b")}},
+			wantIDs:     []string{"a", "b"},
+			wantOffsets: []int{0},
+		},
+		{
+			name:        "stops on an empty page when the total count is too high",
+			stub:        &datasetAPIStub{items: []string{publicItem("a"), publicItem("b"), publicItem("c")}, totalCount: 10},
+			wantIDs:     []string{"a", "b", "c"},
+			wantOffsets: []int{0, 2, 3},
+		},
+		{
+			name:        "returns no datasets for an empty list",
+			stub:        &datasetAPIStub{},
+			wantIDs:     []string{},
+			wantOffsets: []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, closeServer := newTestDatasetAPI(tt.stub, "")
+			defer closeServer()
+
+			datasets, err := d.getDatasets(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids := []string{}
+			for _, dataset := range datasets {
+				ids = append(ids, dataset.ID)
+			}
+
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if fmt.Sprint(tt.stub.offsets) != fmt.Sprint(tt.wantOffsets) {
+				t.Errorf("offsets = %v, want %v", tt.stub.offsets, tt.wantOffsets)
+			}
+		})
+	}
+}
+
+func TestGetDatasetsCurrent(t *testing.T) {
+	stub := &datasetAPIStub{items: []string{publicItem("public"), privateItem("private")}}
+	d, closeServer := newTestDatasetAPI(stub, "")
+	defer closeServer()
+
+	datasets, err := d.getDatasets(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(datasets) != 2 {
+		t.Fatalf("got %d datasets, want 2", len(datasets))
+	}
+
+	public := datasets[0].Current
+	if public == nil || public.Title != "Public public" || public.Links == nil || public.Links.LatestVersion.ID != "1" {
+		t.Errorf("public dataset = %+v, want the top level dataset", public)
+	}
+
+	private := datasets[1].Current
+	if private == nil || private.Title != "Current private" {
+		t.Errorf("private dataset = %+v, want the current dataset", private)
+	}
+}
+
+func TestServiceAuthToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{token: "", want: ""},
+		{token: "secret", want: "Bearer secret"},
+	}
+
+	for _, tt := range tests {
+		stub := &datasetAPIStub{items: []string{publicItem("a")}}
+		d, closeServer := newTestDatasetAPI(stub, tt.token)
+
+		if _, err := d.getDatasets(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := d.getDatasetInstance(context.Background(), "cpih01", "time-series", "3"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		closeServer()
+
+		for _, header := range stub.authHdrs {
+			if header != tt.want {
+				t.Errorf("token %q sent authorization header %q, want %q", tt.token, header, tt.want)
+			}
+		}
+	}
+}
+
+func TestGetDatasetInstance(t *testing.T) {
+	d, closeServer := newTestDatasetAPI(&datasetAPIStub{}, "")
+	defer closeServer()
+
+	version, err := d.getDatasetInstance(context.Background(), "cpih01", "time-series", "3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if version.Version != 3 || len(version.Dimensions) != 1 || version.Dimensions[0].Name != "geography" {
+		t.Errorf("version = %+v, want version 3 with the geography dimension", version)
+	}
+}
+
+func TestDatasetAPIErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{name: "not found", wantErr: "not found in dataset api"},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: "unexpected status code from dataset api: 500"},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantErr: "unexpected status code from dataset api: 503"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, closeServer := newTestDatasetAPI(&datasetAPIStub{status: tt.status}, "")
+			defer closeServer()
+
+			_, err := d.getDatasetInstance(context.Background(), "missing", "time-series", "1")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("instance err = %v, want %q", err, tt.wantErr)
+			}
+
+			if tt.status == 0 {
+				return
+			}
+
+			if _, err = d.getDatasets(context.Background()); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("datasets err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package cmddatasets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/log.go/log"
+)
+
+const (
+	defaultLinkRate    = 5
+	defaultLinkRetries = 2
+	defaultLinkTimeout = 10 * time.Second
+	defaultLinkWorkers = 4
+	linkRetryBackoff   = 500 * time.Millisecond
+)
+
+// linkChecker checks dataset links exist on the ons website, sending requests from a
+// number of workers while keeping to a rate limit across all of them. The wait before
+// each retry doubles from Backoff
+type linkChecker struct {
+	Client  *http.Client
+	Backoff time.Duration
+	Rate    int
+	Retries int
+	Workers int
+}
+
+// newLinkChecker creates a linkChecker sending at most rate requests a second, each
+// request times out after the timeout and failed requests are retried up to retries times
+func newLinkChecker(workers, rate, retries int, timeout time.Duration) *linkChecker {
+	return &linkChecker{
+		Client:  &http.Client{Timeout: timeout},
+		Backoff: linkRetryBackoff,
+		Rate:    rate,
+		Retries: retries,
+		Workers: workers,
+	}
+}
+
+// linkResult is the outcome of checking a single link, a link that does not exist holds
+// the reason in Reason
+type linkResult struct {
+	URL      string `json:"url"`
+	Status   int    `json:"status,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Attempts int    `json:"attempts"`
+}
+
+func (result linkResult) ok() bool {
+	return result.Reason == ""
+}
+
+// brokenLink is a dataset whose link could not be found on the ons website
+type brokenLink struct {
+	DatasetID string `json:"dataset_id"`
+	Title     string `json:"title"`
+	linkResult
+}
+
+// checkLinks checks every link, returning the results in the same order as the links
+func (c *linkChecker) checkLinks(ctx context.Context, links []string) []linkResult {
+	results := make([]linkResult, len(links))
+	jobs := make(chan int)
+
+	limiter := time.NewTicker(time.Second / time.Duration(c.Rate))
+	defer limiter.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j] = c.check(ctx, links[j], limiter.C)
+			}
+		}()
+	}
+
+	for i := range links {
+		jobs <- i
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	return results
+}
+
+// check requests the link until it is found, the site returns a status that will not
+// change on retry, or the retries run out
+func (c *linkChecker) check(ctx context.Context, link string, limiter <-chan time.Time) linkResult {
+	result := linkResult{URL: link}
+
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.Backoff << uint(attempt-1))
+		}
+
+		<-limiter
+		result.Attempts++
+
+		status, err := c.get(ctx, link)
+		result.Status = status
+
+		if err != nil {
+			result.Reason = err.Error()
+		} else if status == http.StatusOK {
+			result.Reason = ""
+			return result
+		} else {
+			result.Reason = fmt.Sprintf("unexpected status code: %d", status)
+
+			// only rate limiting and server errors are worth retrying
+			if status != http.StatusTooManyRequests && status < http.StatusInternalServerError {
+				return result
+			}
+		}
+
+		log.Event(ctx, "failed to check link, retrying", log.WARN, log.Data{"url": link, "attempt": result.Attempts, "status": status, "reason": result.Reason})
+	}
+
+	return result
+}
+
+func (c *linkChecker) get(ctx context.Context, link string) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// drain the body so the connection can be reused
+	if _, err = io.Copy(ioutil.Discard, resp.Body); err != nil {
+		return resp.StatusCode, err
+	}
+
+	return resp.StatusCode, nil
+}
+
+// writeBrokenLinks stores the broken links as a json array in the file
+func writeBrokenLinks(filename string, brokenLinks []brokenLink) error {
+	if brokenLinks == nil {
+		brokenLinks = []brokenLink{}
+	}
+
+	b, err := json.MarshalIndent(brokenLinks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, b, 0644)
+}
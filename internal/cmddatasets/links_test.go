@@ -0,0 +1,169 @@
+package cmddatasets
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// websiteStub stands in for the ons website, responding to each path with its statuses
+// in turn, repeating the last, and recording the requests and connections it receives
+type websiteStub struct {
+	statuses map[string][]int
+
+	mutex       sync.Mutex
+	requests    map[string]int
+	requestedAt []time.Time
+	connections int
+}
+
+func (s *websiteStub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mutex.Lock()
+	s.requests[req.URL.Path]++
+	n := s.requests[req.URL.Path]
+	s.requestedAt = append(s.requestedAt, time.Now())
+	s.mutex.Unlock()
+
+	statuses, ok := s.statuses[req.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if n > len(statuses) {
+		n = len(statuses)
+	}
+
+	// a body larger than the http client drains when it is closed unread, so the
+	// connection is only reused if the checker drains it
+	w.WriteHeader(statuses[n-1])
+	w.Write([]byte(strings.Repeat("<html></html>", 100000)))
+}
+
+func newTestWebsite(stub *websiteStub) *httptest.Server {
+	stub.requests = make(map[string]int)
+
+	srv := httptest.NewUnstartedServer(stub)
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			stub.mutex.Lock()
+			stub.connections++
+			stub.mutex.Unlock()
+		}
+	}
+	srv.Start()
+
+	return srv
+}
+
+func TestCheckLinks(t *testing.T) {
+	stub := &websiteStub{
+		statuses: map[string][]int{
+			"/ok":      {http.StatusOK},
+			"/flaky":   {http.StatusServiceUnavailable, http.StatusOK},
+			"/limited": {http.StatusTooManyRequests, http.StatusOK},
+			"/down":    {http.StatusInternalServerError},
+			"/gone":    {http.StatusGone, http.StatusOK},
+		},
+	}
+	srv := newTestWebsite(stub)
+	defer srv.Close()
+
+	checker := newLinkChecker(3, 1000, 2, time.Second)
+	checker.Backoff = time.Millisecond
+
+	paths := []string{"/ok", "/flaky", "/missing", "/limited", "/down", "/gone"}
+	var links []string
+	for _, path := range paths {
+		links = append(links, srv.URL+path)
+	}
+
+	results := checker.checkLinks(context.Background(), links)
+
+	want := []linkResult{
+		{URL: links[0], Status: http.StatusOK, Attempts: 1},
+		{URL: links[1], Status: http.StatusOK, Attempts: 2},
+		{URL: links[2], Status: http.StatusNotFound, Reason: "unexpected status code: 404", Attempts: 1},
+		{URL: links[3], Status: http.StatusOK, Attempts: 2},
+		{URL: links[4], Status: http.StatusInternalServerError, Reason: "unexpected status code: 500", Attempts: 3},
+		{URL: links[5], Status: http.StatusGone, Reason: "unexpected status code: 410", Attempts: 1},
+	}
+
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %+v", results, want)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want[i])
+		}
+		if results[i].ok() != (want[i].Reason == "") {
+			t.Errorf("result %d ok = %t, want %t", i, results[i].ok(), want[i].Reason == "")
+		}
+	}
+}
+
+func TestCheckLinksRateLimit(t *testing.T) {
+	stub := &websiteStub{statuses: map[string][]int{"/ok": {http.StatusOK}}}
+	srv := newTestWebsite(stub)
+	defer srv.Close()
+
+	links := make([]string, 6)
+	for i := range links {
+		links[i] = srv.URL + "/ok"
+	}
+
+	// the rate is shared by every worker, so six requests at 20 a second take at least 250ms
+	checker := newLinkChecker(4, 20, 0, time.Second)
+
+	start := time.Now()
+	checker.checkLinks(context.Background(), links)
+
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("checked %d links in %s, want the rate limit kept", len(links), elapsed)
+	}
+
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	for i := 1; i < len(stub.requestedAt); i++ {
+		if gap := stub.requestedAt[i].Sub(stub.requestedAt[i-1]); gap < 25*time.Millisecond {
+			t.Errorf("request %d sent %s after the previous one, want the requests spaced out by the rate", i, gap)
+		}
+	}
+}
+
+func TestCheckLinksDrainsBody(t *testing.T) {
+	stub := &websiteStub{statuses: map[string][]int{"/ok": {http.StatusOK}, "/missing": {http.StatusNotFound}}}
+	srv := newTestWebsite(stub)
+	defer srv.Close()
+
+	links := []string{srv.URL + "/ok", srv.URL + "/missing", srv.URL + "/ok", srv.URL + "/missing"}
+
+	// a single worker reuses its connection for every request once each body is drained
+	checker := newLinkChecker(1, 1000, 0, time.Second)
+	checker.checkLinks(context.Background(), links)
+
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	if stub.connections != 1 {
+		t.Errorf("connections = %d, want 1", stub.connections)
+	}
+}
+
+func TestCheckLinksConnectionError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	link := srv.URL + "/ok"
+	srv.Close()
+
+	checker := newLinkChecker(1, 1000, 1, time.Second)
+	checker.Backoff = time.Millisecond
+
+	results := checker.checkLinks(context.Background(), []string{link})
+
+	if results[0].ok() || results[0].Status != 0 || results[0].Attempts != 2 {
+		t.Errorf("result = %+v, want a failure after retrying", results[0])
+	}
+}
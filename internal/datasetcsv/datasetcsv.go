@@ -0,0 +1,161 @@
+package datasetcsv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A list of the headers in a csv of datasets
+const (
+	HeaderAlias           = "alias"
+	HeaderDescription     = "description"
+	HeaderDimensionLabels = "dimension-labels"
+	HeaderDimensionNames  = "dimension-names"
+	HeaderLink            = "ons-link"
+	HeaderTitle           = "title"
+	HeaderTopic           = "topic"
+)
+
+// DimensionSeparator separates the dimension names, and the dimension labels, within a column
+const DimensionSeparator = ":"
+
+// ErrDimensionSeparator is returned by Write for a dimension name or label containing the
+// separator, as it could not be split back into the same dimensions
+var ErrDimensionSeparator = errors.New("dimension contains the separator " + DimensionSeparator)
+
+// Headers is the header row of a csv of datasets, in the order the columns are written
+var Headers = []string{
+	HeaderTitle,
+	HeaderAlias,
+	HeaderDescription,
+	HeaderTopic,
+	HeaderLink,
+	HeaderDimensionNames,
+	HeaderDimensionLabels,
+}
+
+// Row represents a dataset in a csv of datasets, the dimension names and labels are in
+// the same order
+type Row struct {
+	Alias           string
+	Description     string
+	DimensionLabels []string
+	DimensionNames  []string
+	Link            string
+	Title           string
+	Topic           string
+}
+
+// values returns the columns of the row in the order of the headers
+func (row Row) values() []string {
+	return []string{
+		row.Title,
+		row.Alias,
+		row.Description,
+		row.Topic,
+		row.Link,
+		strings.Join(row.DimensionNames, DimensionSeparator),
+		strings.Join(row.DimensionLabels, DimensionSeparator),
+	}
+}
+
+// Writer writes a csv of datasets to a temporary file, which replaces the file once the
+// writer is committed, so the file is never left partly written
+type Writer struct {
+	filename string
+	file     *os.File
+	csv      *csv.Writer
+}
+
+// Create creates a Writer for the file and writes the header row
+func Create(filename string) (*Writer, error) {
+	file, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+"-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		filename: filename,
+		file:     file,
+		csv:      csv.NewWriter(file),
+	}
+
+	if err = w.csv.Write(Headers); err != nil {
+		w.Abort()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write writes a row to the temporary file, quoting any column that needs it, a row with
+// a dimension name or label containing the separator is not written
+func (w *Writer) Write(row Row) error {
+	if err := row.checkDimensions(); err != nil {
+		return err
+	}
+
+	return w.csv.Write(row.values())
+}
+
+// checkDimensions returns an error wrapping ErrDimensionSeparator for the first dimension
+// name or label containing the separator
+func (row Row) checkDimensions() error {
+	for _, name := range row.DimensionNames {
+		if strings.Contains(name, DimensionSeparator) {
+			return fmt.Errorf("dimension name %q: %w", name, ErrDimensionSeparator)
+		}
+	}
+
+	for _, label := range row.DimensionLabels {
+		if strings.Contains(label, DimensionSeparator) {
+			return fmt.Errorf("dimension label %q: %w", label, ErrDimensionSeparator)
+		}
+	}
+
+	return nil
+}
+
+// Commit flushes the rows to the temporary file and renames it over the file
+func (w *Writer) Commit() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		w.Abort()
+		return err
+	}
+
+	if err := w.file.Sync(); err != nil {
+		w.Abort()
+		return err
+	}
+
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+
+	// temporary files are only readable by their owner
+	if err := os.Chmod(w.file.Name(), 0644); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+
+	if err := os.Rename(w.file.Name(), w.filename); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+
+	return nil
+}
+
+// Abort removes the temporary file, leaving the file untouched
+func (w *Writer) Abort() error {
+	w.file.Close()
+
+	return os.Remove(w.file.Name())
+}
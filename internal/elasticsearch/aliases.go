@@ -0,0 +1,145 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	errs "github.com/ONSdigital/dp-census-dataset-search-api/apierrors"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// aliasActions represents the body of a request to the elasticsearch aliases api,
+// all actions in a single request are applied atomically
+type aliasActions struct {
+	Actions []map[string]aliasAction `json:"actions"`
+}
+
+type aliasAction struct {
+	Index string `json:"index"`
+	Alias string `json:"alias,omitempty"`
+}
+
+// IndexExists checks whether an index, or an alias, with the given name exists
+func (api *API) IndexExists(ctx context.Context, indexName string) (bool, int, error) {
+	path := "/" + indexName
+
+	_, status, err := api.CallElastic(ctx, path, "HEAD", nil)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return false, status, nil
+		}
+
+		return false, status, err
+	}
+
+	return true, status, nil
+}
+
+// RefreshIndex makes all documents written to an index available to search and count
+func (api *API) RefreshIndex(ctx context.Context, indexName string) (int, error) {
+	path := "/" + indexName + "/_refresh"
+
+	_, status, err := api.CallElastic(ctx, path, "POST", nil)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
+// GetAliasIndices retrieves the names of the indices an alias points to, an alias
+// that does not exist points to no indices
+func (api *API) GetAliasIndices(ctx context.Context, alias string) ([]string, int, error) {
+	path := "/_alias/" + alias
+
+	responseBody, status, err := api.CallElastic(ctx, path, "GET", nil)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return nil, status, nil
+		}
+
+		return nil, status, err
+	}
+
+	var aliases map[string]interface{}
+	if err = json.Unmarshal(responseBody, &aliases); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return nil, status, errs.ErrUnmarshallingJSON
+	}
+
+	indices := make([]string, 0, len(aliases))
+	for index := range aliases {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	return indices, status, nil
+}
+
+// ListIndices retrieves the names of indices matching the pattern, which may contain wildcards
+func (api *API) ListIndices(ctx context.Context, pattern string) ([]string, int, error) {
+	path := "/_cat/indices/" + pattern + "?format=json&h=index"
+
+	responseBody, status, err := api.CallElastic(ctx, path, "GET", nil)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return nil, status, nil
+		}
+
+		return nil, status, err
+	}
+
+	var rows []struct {
+		Index string `json:"index"`
+	}
+	if err = json.Unmarshal(responseBody, &rows); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return nil, status, errs.ErrUnmarshallingJSON
+	}
+
+	indices := make([]string, 0, len(rows))
+	for _, row := range rows {
+		indices = append(indices, row.Index)
+	}
+	sort.Strings(indices)
+
+	return indices, status, nil
+}
+
+// SwapAlias atomically points the alias at the new index, removing it from the old
+// indices. Any index in removeIndices is deleted in the same request, which allows
+// an index to be replaced by an alias of the same name
+func (api *API) SwapAlias(ctx context.Context, alias, newIndex string, oldIndices, removeIndices []string) (int, error) {
+	path := "/_aliases"
+
+	actions := aliasActions{}
+	for _, index := range oldIndices {
+		actions.Actions = append(actions.Actions, map[string]aliasAction{
+			"remove": {Index: index, Alias: alias},
+		})
+	}
+
+	for _, index := range removeIndices {
+		actions.Actions = append(actions.Actions, map[string]aliasAction{
+			"remove_index": {Index: index},
+		})
+	}
+
+	actions.Actions = append(actions.Actions, map[string]aliasAction{
+		"add": {Index: newIndex, Alias: alias},
+	})
+
+	bytes, err := json.Marshal(actions)
+	if err != nil {
+		return 0, err
+	}
+
+	_, status, err := api.CallElastic(ctx, path, "POST", bytes)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
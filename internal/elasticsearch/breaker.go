@@ -0,0 +1,83 @@
+package elasticsearch
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker stops requests being sent to elasticsearch after a number of
+// consecutive failures, so a struggling cluster is given time to recover. Once
+// the cooldown has passed a single trial request is let through, closing the
+// circuit if it succeeds or opening it for another cooldown if it fails
+type CircuitBreaker struct {
+	cooldown  time.Duration
+	threshold int
+
+	mutex    sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	trial    bool
+}
+
+// NewCircuitBreaker creates a CircuitBreaker that opens after threshold consecutive failures
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		cooldown:  cooldown,
+		threshold: threshold,
+	}
+}
+
+// Allow reports whether a request can be sent, if not it returns how long
+// until the circuit will let a trial request through
+func (b *CircuitBreaker) Allow() (bool, time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.open {
+		return true, 0
+	}
+
+	remaining := b.cooldown - time.Since(b.openedAt)
+	if remaining > 0 || b.trial {
+		if remaining < time.Second {
+			remaining = time.Second
+		}
+		return false, remaining
+	}
+
+	b.trial = true
+	return true, 0
+}
+
+// Success records a successful request, closing the circuit
+func (b *CircuitBreaker) Success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures = 0
+	b.open = false
+	b.trial = false
+}
+
+// Failure records a failed request, opening the circuit if the threshold is reached or the trial failed
+func (b *CircuitBreaker) Failure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	if b.trial || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = time.Now()
+		b.trial = false
+	}
+}
+
+// Abandon records a request given up by the caller, which says nothing about the health
+// of elasticsearch. If the circuit is waiting on a trial request another one is let through
+func (b *CircuitBreaker) Abandon() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.trial = false
+}
@@ -0,0 +1,93 @@
+package elasticsearch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+
+	open := func(t *testing.T) *CircuitBreaker {
+		b := NewCircuitBreaker(2, cooldown)
+		b.Failure()
+		if allowed, _ := b.Allow(); !allowed {
+			t.Fatal("circuit opened before the threshold was reached")
+		}
+		b.Failure()
+		return b
+	}
+
+	t.Run("opens after the threshold and rejects during the cooldown", func(t *testing.T) {
+		b := open(t)
+
+		allowed, retryAfter := b.Allow()
+		if allowed {
+			t.Fatal("request allowed while the circuit is open")
+		}
+		if retryAfter < time.Second {
+			t.Errorf("retry after = %s, want at least 1s", retryAfter)
+		}
+	})
+
+	t.Run("a success resets the consecutive failures", func(t *testing.T) {
+		b := NewCircuitBreaker(2, cooldown)
+		b.Failure()
+		b.Success()
+		b.Failure()
+
+		if allowed, _ := b.Allow(); !allowed {
+			t.Fatal("circuit opened after failures that were not consecutive")
+		}
+	})
+
+	t.Run("lets a single trial through after the cooldown", func(t *testing.T) {
+		b := open(t)
+		time.Sleep(cooldown)
+
+		if allowed, _ := b.Allow(); !allowed {
+			t.Fatal("trial request not allowed after the cooldown")
+		}
+		if allowed, _ := b.Allow(); allowed {
+			t.Fatal("second request allowed while the trial is in flight")
+		}
+	})
+
+	t.Run("closes when the trial succeeds", func(t *testing.T) {
+		b := open(t)
+		time.Sleep(cooldown)
+		b.Allow()
+		b.Success()
+
+		if allowed, _ := b.Allow(); !allowed {
+			t.Fatal("request not allowed after a successful trial")
+		}
+	})
+
+	t.Run("opens for another cooldown when the trial fails", func(t *testing.T) {
+		b := open(t)
+		time.Sleep(cooldown)
+		b.Allow()
+		b.Failure()
+
+		if allowed, _ := b.Allow(); allowed {
+			t.Fatal("request allowed after a failed trial")
+		}
+
+		time.Sleep(cooldown)
+		if allowed, _ := b.Allow(); !allowed {
+			t.Fatal("trial request not allowed after the second cooldown")
+		}
+	})
+
+	t.Run("lets another trial through when the trial is abandoned", func(t *testing.T) {
+		b := open(t)
+		time.Sleep(cooldown)
+		b.Allow()
+		b.Abandon()
+
+		if allowed, _ := b.Allow(); !allowed {
+			t.Fatal("trial request not allowed after the trial was abandoned")
+		}
+	})
+}
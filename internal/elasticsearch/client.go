@@ -0,0 +1,77 @@
+package elasticsearch
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	dphttp "github.com/ONSdigital/dp-net/http"
+)
+
+// Auth holds how requests to elasticsearch are authenticated, only one method
+// is used and they take precedence in the order: signer, api key, basic auth
+type Auth struct {
+	APIKey   string
+	Password string
+	Signer   *Signer
+	Username string
+}
+
+// TLSConfig holds the certificates used to connect to elasticsearch over https,
+// when no CA bundle is set the host's root certificate authorities are used
+type TLSConfig struct {
+	CACertFile     string
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+// NewClient creates an http client for calling elasticsearch, using the custom
+// CA bundle and client certificate if set in the tls config. The client has no
+// overall timeout as timeouts are set per request by the API's policy
+func NewClient(tlsConfig TLSConfig) (dphttp.Clienter, error) {
+	config := &tls.Config{}
+
+	if tlsConfig.CACertFile != "" {
+		caCert, err := ioutil.ReadFile(tlsConfig.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("no certificates found in CA bundle: " + tlsConfig.CACertFile)
+		}
+
+		config.RootCAs = pool
+	}
+
+	if tlsConfig.ClientCertFile != "" || tlsConfig.ClientKeyFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(tlsConfig.ClientCertFile, tlsConfig.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{clientCert}
+	}
+
+	// Mirror the dp-net default client, with a client and transport of its own so
+	// settings do not leak into other clients sharing the dp-net default client
+	return &dphttp.Client{
+		RetryTime: dphttp.DefaultClient.RetryTime,
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout: 5 * time.Second,
+				}).DialContext,
+				TLSClientConfig:     config,
+				TLSHandshakeTimeout: 5 * time.Second,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     30 * time.Second,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,160 @@
+package elasticsearch
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate creates a self signed certificate, writing it and its key to pem files
+// in the directory, and returns the certificate along with the filenames
+func writeCertificate(t *testing.T, dir, name string) (tls.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	writeTestFile(t, certFile, certPEM)
+	writeTestFile(t, keyFile, keyPEM)
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, certFile, keyFile
+}
+
+func writeTestFile(t *testing.T, filename string, contents []byte) {
+	if err := ioutil.WriteFile(filename, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewClientTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "elasticsearch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clientCert, clientCertFile, clientKeyFile := writeCertificate(t, dir, "client")
+	leaf, err := x509.ParseCertificate(clientCert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(leaf)
+
+	// the stand-in elasticsearch only accepts connections presenting the client certificate
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writeTestFile(t, caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	invalidCAFile := filepath.Join(dir, "invalid.pem")
+	writeTestFile(t, invalidCAFile, []byte("not a certificate"))
+
+	tests := []struct {
+		name       string
+		tlsConfig  TLSConfig
+		wantErr    bool
+		wantCallOK bool
+	}{
+		{
+			name:       "CA bundle and client certificate",
+			tlsConfig:  TLSConfig{CACertFile: caFile, ClientCertFile: clientCertFile, ClientKeyFile: clientKeyFile},
+			wantCallOK: true,
+		},
+		{
+			name:      "no CA bundle, the server certificate is not trusted",
+			tlsConfig: TLSConfig{ClientCertFile: clientCertFile, ClientKeyFile: clientKeyFile},
+		},
+		{
+			name:      "no client certificate",
+			tlsConfig: TLSConfig{CACertFile: caFile},
+		},
+		{
+			name:      "missing CA bundle",
+			tlsConfig: TLSConfig{CACertFile: filepath.Join(dir, "missing.pem")},
+			wantErr:   true,
+		},
+		{
+			name:      "CA bundle without certificates",
+			tlsConfig: TLSConfig{CACertFile: invalidCAFile},
+			wantErr:   true,
+		},
+		{
+			name:      "client certificate without key",
+			tlsConfig: TLSConfig{CACertFile: caFile, ClientCertFile: clientCertFile},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli, err := NewClient(tt.tlsConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			api, err := NewElasticSearchAPI(cli, []string{srv.URL}, Auth{}, Policy{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer api.Close()
+
+			_, _, err = api.CallElastic(context.Background(), "/_cluster/health", http.MethodGet, nil)
+			if (err == nil) != tt.wantCallOK {
+				t.Errorf("call err = %v, want success %t", err, tt.wantCallOK)
+			}
+		})
+	}
+}
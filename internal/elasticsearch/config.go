@@ -0,0 +1,59 @@
+package elasticsearch
+
+import (
+	"context"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/config"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// NewAPIFromConfig creates an elasticsearch API client from the configuration, without
+// sending any request to elasticsearch. It is used by the service and the scripts alike
+// so that both connect to elasticsearch the same way
+func NewAPIFromConfig(ctx context.Context, cfg *config.Config) (*API, error) {
+	auth := Auth{
+		APIKey:   cfg.ElasticSearchAPIKey,
+		Password: cfg.ElasticSearchPassword,
+		Username: cfg.ElasticSearchUsername,
+	}
+
+	var err error
+	if cfg.SignElasticsearchRequests {
+		auth.Signer, err = NewSigner(cfg.AWSRegion, cfg.AWSService)
+		if err != nil {
+			log.Event(ctx, "failed to create aws request signer", log.ERROR, log.Error(err), log.Data{"aws_region": cfg.AWSRegion, "aws_service": cfg.AWSService})
+			return nil, err
+		}
+	}
+
+	cli, err := NewClient(TLSConfig{
+		CACertFile:     cfg.ElasticSearchCACertFile,
+		ClientCertFile: cfg.ElasticSearchClientCertFile,
+		ClientKeyFile:  cfg.ElasticSearchClientKeyFile,
+	})
+	if err != nil {
+		log.Event(ctx, "failed to create elasticsearch http client", log.ERROR, log.Error(err))
+		return nil, err
+	}
+
+	policy := Policy{
+		SearchTimeout: cfg.ElasticSearchSearchTimeout,
+		GetTimeout:    cfg.ElasticSearchGetTimeout,
+		WriteTimeout:  cfg.ElasticSearchWriteTimeout,
+		MaxRetries:    cfg.ElasticSearchMaxRetries,
+		RetryBackoff:  cfg.ElasticSearchRetryBackoff,
+		TotalTimeout:  cfg.ElasticSearchTotalTimeout,
+	}
+
+	if cfg.CircuitBreakerThreshold > 0 {
+		policy.Breaker = NewCircuitBreaker(cfg.CircuitBreakerThreshold, cfg.CircuitBreakerCooldown)
+	}
+
+	esAPI, err := NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURLs, auth, policy)
+	if err != nil {
+		log.Event(ctx, "failed to create elasticsearch api", log.ERROR, log.Error(err))
+		return nil, err
+	}
+
+	return esAPI, nil
+}
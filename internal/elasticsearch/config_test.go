@@ -0,0 +1,112 @@
+package elasticsearch
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/config"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	cfg := &config.Config{
+		AWSRegion:             "eu-west-1",
+		ElasticSearchAPIURLs:  []string{"http://localhost:9200"},
+		ElasticSearchUsername: "from-env",
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(fs, cfg)
+
+	if got := fs.Lookup("elasticsearch-url").DefValue; got != "http://localhost:9200" {
+		t.Errorf("url default = %q, want the configured url", got)
+	}
+
+	err := fs.Parse([]string{"-elasticsearch-url=http://node1:9200,http://node2:9200", "-sign-requests", "-aws-region=eu-west-2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := &config.Config{
+		AWSRegion:                 "eu-west-2",
+		ElasticSearchAPIURLs:      []string{"http://node1:9200", "http://node2:9200"},
+		ElasticSearchUsername:     "from-env",
+		SignElasticsearchRequests: true,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestNewAPIFromConfig(t *testing.T) {
+	defer setAWSCredentials(t)()
+
+	var mutex sync.Mutex
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		authorization = req.Header.Get("Authorization")
+		mutex.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		cfg        config.Config
+		wantPrefix string
+		wantErr    bool
+	}{
+		{
+			name:       "basic auth",
+			cfg:        config.Config{ElasticSearchUsername: "user", ElasticSearchPassword: "pass"},
+			wantPrefix: "Basic ",
+		},
+		{
+			name:       "api key",
+			cfg:        config.Config{ElasticSearchAPIKey: "key", ElasticSearchUsername: "user"},
+			wantPrefix: "ApiKey key",
+		},
+		{
+			name:       "signed requests",
+			cfg:        config.Config{SignElasticsearchRequests: true, AWSRegion: "eu-west-1", AWSService: "es", ElasticSearchAPIKey: "key"},
+			wantPrefix: "AWS4-HMAC-SHA256 ",
+		},
+		{
+			name:    "missing CA bundle",
+			cfg:     config.Config{ElasticSearchCACertFile: "missing.pem"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.ElasticSearchAPIURLs = []string{srv.URL}
+
+			api, err := NewAPIFromConfig(context.Background(), &cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer api.Close()
+
+			if _, _, err = api.CallElastic(context.Background(), "/_cluster/health", http.MethodGet, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if !strings.HasPrefix(authorization, tt.wantPrefix) {
+				t.Errorf("authorization = %q, want prefix %q", authorization, tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -8,35 +8,73 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"time"
 
 	errs "github.com/ONSdigital/dp-census-dataset-search-api/apierrors"
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/metrics"
 	"github.com/ONSdigital/dp-census-dataset-search-api/models"
 	dphttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/log"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // ErrorUnexpectedStatusCode represents the error message to be returned when
 // the status received from elastic is not as expected
 var ErrorUnexpectedStatusCode = errors.New("unexpected status code from api")
 
-// API aggregates a client and URL and other common data for accessing the API
+// API aggregates a client and node URLs and other common data for accessing the API
 type API struct {
+	auth     Auth
 	clienter dphttp.Clienter
-	url      string
+	nodes    *nodePool
+	policy   Policy
+	metrics  *metrics.Elasticsearch
 }
 
-// NewElasticSearchAPI creates an ElasticSearchAPI object
-func NewElasticSearchAPI(clienter dphttp.Clienter, elasticSearchAPIURL string) *API {
+// NewElasticSearchAPI creates an ElasticSearchAPI object, requests are spread across
+// the node URLs in turn, authenticated with the method set in auth (if any) and
+// retried according to the policy, so retries built into the client are disabled, it
+// returns ErrNoNodes when there are no node URLs
+func NewElasticSearchAPI(clienter dphttp.Clienter, elasticSearchAPIURLs []string, auth Auth, policy Policy) (*API, error) {
+	nodes, err := newNodePool(elasticSearchAPIURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	clienter.SetMaxRetries(0)
 
 	return &API{
+		auth:     auth,
 		clienter: clienter,
-		url:      elasticSearchAPIURL,
+		nodes:    nodes,
+		policy:   policy,
+	}, nil
+}
+
+// RegisterMetrics records the requests made to elasticsearch in metrics registered with
+// the registerer, no metrics are recorded until it is called
+func (api *API) RegisterMetrics(reg prometheus.Registerer) error {
+	m, err := metrics.NewElasticsearch(reg)
+	if err != nil {
+		return err
+	}
+
+	api.metrics = m
+	return nil
+}
+
+// Close stops probing dead nodes and closes idle connections to elasticsearch
+func (api *API) Close() {
+	api.StopNodeCheck()
+
+	if cli, ok := api.clienter.(*dphttp.Client); ok && cli.HTTPClient != nil {
+		cli.HTTPClient.CloseIdleConnections()
 	}
 }
 
 // CreateSearchIndex creates a new index in elastic search
 func (api *API) CreateSearchIndex(ctx context.Context, indexName string, mappingsFile string) (int, error) {
-	path := api.url + "/" + indexName
+	path := "/" + indexName
 
 	indexMappings, err := Asset(mappingsFile)
 	if err != nil {
@@ -53,7 +91,7 @@ func (api *API) CreateSearchIndex(ctx context.Context, indexName string, mapping
 
 // DeleteSearchIndex removes an index from elastic search
 func (api *API) DeleteSearchIndex(ctx context.Context, indexName string) (int, error) {
-	path := api.url + "/" + indexName
+	path := "/" + indexName
 
 	_, status, err := api.CallElastic(ctx, path, "DELETE", nil)
 	if err != nil {
@@ -65,7 +103,7 @@ func (api *API) DeleteSearchIndex(ctx context.Context, indexName string) (int, e
 
 // AddDocument adds a document to an elasticsearch index
 func (api *API) AddDocument(ctx context.Context, indexName string, bytes []byte) (int, error) {
-	path := api.url + "/" + indexName + "/_doc"
+	path := "/" + indexName + "/_doc"
 	logData := log.Data{"path": path}
 
 	log.Event(ctx, "adding dataset", log.INFO, logData)
@@ -78,35 +116,102 @@ func (api *API) AddDocument(ctx context.Context, indexName string, bytes []byte)
 	return status, nil
 }
 
-// BulkRequest ...
-func (api *API) BulkRequest(ctx context.Context, indexName string, documents []interface{}) (int, error) {
-	path := api.url + "/_bulk"
+// PutDocument creates or replaces a document with the given id in an elasticsearch index
+func (api *API) PutDocument(ctx context.Context, indexName, id string, bytes []byte) (int, error) {
+	path := "/" + indexName + "/_doc/" + url.PathEscape(id)
+	logData := log.Data{"path": path}
+
+	log.Event(ctx, "putting document", log.INFO, logData)
+
+	_, status, err := api.CallElastic(ctx, path, "PUT", bytes)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
+// GetDocument retrieves the source of a document with the given id from an elasticsearch index
+func (api *API) GetDocument(ctx context.Context, indexName, id string) ([]byte, int, error) {
+	path := "/" + indexName + "/_doc/" + url.PathEscape(id) + "/_source"
+
+	responseBody, status, err := api.CallElastic(ctx, path, "GET", nil)
+	if err != nil {
+		return nil, status, err
+	}
+
+	return responseBody, status, nil
+}
+
+// A list of actions that can be taken on a document in a bulk request
+const (
+	BulkIndex  = "index"
+	BulkDelete = "delete"
+)
+
+// BulkAction is a single action on the document with the given id within a bulk
+// request, the document is only sent for index actions
+type BulkAction struct {
+	Action   string
+	ID       string
+	Document interface{}
+}
+
+type bulkActionMetadata struct {
+	Index string `json:"_index"`
+	Type  string `json:"_type"`
+	ID    string `json:"_id,omitempty"`
+}
+
+// BulkRequest sends the actions in a single request, the response holds the result
+// of each action in order as actions can fail even when the request succeeds
+func (api *API) BulkRequest(ctx context.Context, indexName string, actions []BulkAction) (*models.BulkResponse, int, error) {
+	path := "/_bulk"
 
 	var bulk []byte
 
-	for _, doc := range documents {
+	for _, action := range actions {
+		metadata, err := json.Marshal(map[string]bulkActionMetadata{
+			action.Action: {Index: indexName, Type: "_doc", ID: action.ID},
+		})
+		if err != nil {
+			return nil, 0, err
+		}
 
-		b, err := json.Marshal(doc)
+		bulk = append(bulk, metadata...)
+		bulk = append(bulk, []byte("\n")...)
+
+		if action.Action == BulkDelete {
+			continue
+		}
+
+		b, err := json.Marshal(action.Document)
 		if err != nil {
-			return 0, err
+			return nil, 0, err
 		}
 
-		bulk = append(bulk, []byte("{ \"index\": {\"_index\": \""+indexName+"\", \"_type\": \"_doc\"} }\n")...) // It may need an ID?
 		bulk = append(bulk, b...)
 		bulk = append(bulk, []byte("\n")...)
 	}
 
-	_, status, err := api.CallElastic(ctx, path, "POST", bulk)
+	responseBody, status, err := api.CallElastic(ctx, path, "POST", bulk)
 	if err != nil {
-		return status, err
+		return nil, status, err
 	}
 
-	return status, nil
+	response := &models.BulkResponse{}
+
+	if err = json.Unmarshal(responseBody, response); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return nil, status, errs.ErrUnmarshallingJSON
+	}
+
+	return response, status, nil
 }
 
 // SingleRequest ...
 func (api *API) SingleRequest(ctx context.Context, indexName string, document interface{}) (int, error) {
-	path := api.url + "/" + indexName + "/_doc"
+	path := "/" + indexName + "/_doc"
 
 	bytes, err := json.Marshal(document)
 	if err != nil {
@@ -124,7 +229,7 @@ func (api *API) SingleRequest(ctx context.Context, indexName string, document in
 // QueryDatasetSearch ...
 func (api *API) QueryDatasetSearch(ctx context.Context, indexName string, query interface{}, limit, offset int) (*models.SearchResponse, int, error) {
 
-	path := api.url + "/" + indexName + "/_search"
+	path := "/" + indexName + "/_search"
 	logData := log.Data{"query": query, "path": path}
 
 	log.Event(ctx, "find documents based on search term", log.INFO, logData)
@@ -137,6 +242,11 @@ func (api *API) QueryDatasetSearch(ctx context.Context, indexName string, query
 	responseBody, status, err := api.CallElastic(ctx, path, "GET", bytes)
 	logData["status"] = status
 	if err != nil {
+		var unavailable *errs.UnavailableError
+		if errors.As(err, &unavailable) {
+			return nil, status, err
+		}
+
 		if status >= 500 {
 			log.Event(ctx, "failed to call elasticsearch", log.ERROR, log.Error(err), logData)
 			return nil, status, errs.ErrIndexNotFound
@@ -157,26 +267,228 @@ func (api *API) QueryDatasetSearch(ctx context.Context, indexName string, query
 	return response, status, nil
 }
 
-// CallElastic builds a request to elastic search based on the method, path and payload
-func (api *API) CallElastic(ctx context.Context, path, method string, payload interface{}) ([]byte, int, error) {
-	logData := log.Data{"url": path, "method": method}
+// scrollResponse represents a page of documents from the elasticsearch scroll api
+type scrollResponse struct {
+	ScrollID string `json:"_scroll_id"`
+	Hits     struct {
+		Hits []struct {
+			ID     string          `json:"_id"`
+			Source json.RawMessage `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
 
-	URL, err := url.Parse(path)
+// GetAllDocuments retrieves the source of every document in an index by id, paging
+// through the index with the scroll api
+func (api *API) GetAllDocuments(ctx context.Context, indexName string) (map[string]json.RawMessage, int, error) {
+	path := "/" + indexName + "/_search?scroll=1m"
+	body := []byte(`{"size":1000,"sort":["_doc"]}`)
+
+	documents := make(map[string]json.RawMessage)
+	var scrollID string
+
+	defer func() {
+		if scrollID == "" {
+			return
+		}
+
+		clear, _ := json.Marshal(map[string]string{"scroll_id": scrollID})
+		if _, status, err := api.CallElastic(ctx, "/_search/scroll", "DELETE", clear); err != nil {
+			log.Event(ctx, "failed to clear scroll", log.WARN, log.Error(err), log.Data{"status": status})
+		}
+	}()
+
+	for {
+		responseBody, status, err := api.CallElastic(ctx, path, "POST", body)
+		if err != nil {
+			return nil, status, err
+		}
+
+		response := &scrollResponse{}
+		if err = json.Unmarshal(responseBody, response); err != nil {
+			log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+			return nil, status, errs.ErrUnmarshallingJSON
+		}
+
+		scrollID = response.ScrollID
+
+		if len(response.Hits.Hits) == 0 {
+			return documents, status, nil
+		}
+
+		for _, hit := range response.Hits.Hits {
+			documents[hit.ID] = hit.Source
+		}
+
+		path = "/_search/scroll"
+		body, err = json.Marshal(map[string]string{"scroll": "1m", "scroll_id": scrollID})
+		if err != nil {
+			return nil, status, err
+		}
+	}
+}
+
+// GetClusterHealth retrieves the health of the elasticsearch cluster
+func (api *API) GetClusterHealth(ctx context.Context) (*models.ClusterHealth, int, error) {
+	path := "/_cluster/health"
+
+	responseBody, status, err := api.CallElastic(ctx, path, "GET", nil)
 	if err != nil {
-		log.Event(ctx, "failed to create url for elastic call", log.ERROR, log.Error(err), logData)
-		return nil, 0, err
+		return nil, status, err
 	}
-	path = URL.String()
-	logData["url"] = path
 
-	var req *http.Request
+	health := &models.ClusterHealth{}
+
+	if err = json.Unmarshal(responseBody, health); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return nil, status, errs.ErrUnmarshallingJSON
+	}
+
+	return health, status, nil
+}
+
+// CountDocuments retrieves the number of documents stored in an elasticsearch index
+func (api *API) CountDocuments(ctx context.Context, indexName string) (int, int, error) {
+	path := "/" + indexName + "/_count"
+
+	responseBody, status, err := api.CallElastic(ctx, path, "GET", nil)
+	if err != nil {
+		return 0, status, err
+	}
 
+	count := &models.CountResponse{}
+
+	if err = json.Unmarshal(responseBody, count); err != nil {
+		log.Event(ctx, "unable to unmarshal json body", log.ERROR, log.Error(err))
+		return 0, status, errs.ErrUnmarshallingJSON
+	}
+
+	return count.Count, status, nil
+}
+
+// CallElastic builds a request to elastic search based on the method, path and payload,
+// the path is relative to the node the request is sent to. Requests that cannot connect
+// to a node fail over to the next node, other failures follow the timeouts, retries
+// and circuit breaker set in the API's policy
+func (api *API) CallElastic(ctx context.Context, path, method string, payload interface{}) ([]byte, int, error) {
+	logData := log.Data{"path": path, "method": method}
+
+	var body []byte
 	if payload != nil {
-		req, err = http.NewRequest(method, path, bytes.NewReader(payload.([]byte)))
-		req.Header.Add("Content-type", "application/json")
-		logData["payload"] = string(payload.([]byte))
+		body = payload.([]byte)
+		logData["payload"] = string(body)
+	}
+
+	var responseBody []byte
+	var status int
+	var err error
+
+	if breaker := api.policy.Breaker; breaker != nil {
+		if allowed, retryAfter := breaker.Allow(); !allowed {
+			err := &errs.UnavailableError{RetryAfter: retryAfter}
+			log.Event(ctx, "circuit open, not calling elastic", log.WARN, log.Error(err), logData)
+			return nil, 0, err
+		}
+
+		// the outcome is recorded on every return, otherwise a trial request that does not
+		// record one would leave the circuit open for good
+		defer func() {
+			switch {
+			case ctx.Err() != nil:
+				breaker.Abandon()
+			case isFailure(status, err):
+				breaker.Failure()
+			default:
+				breaker.Success()
+			}
+		}()
+	}
+
+	operation := operationKind(method, path)
+	maxRetries := 0
+	if retryable(operation) {
+		maxRetries = api.policy.MaxRetries
+	}
+
+	// a search or get is bounded as a whole, so a request served by the api has given up
+	// on its retries before the http server gives up on writing the response
+	requestCtx := ctx
+	if retryable(operation) && api.policy.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		requestCtx, cancel = context.WithTimeout(ctx, api.policy.TotalTimeout)
+		defer cancel()
+	}
+
+	for attempt, failovers := 0, 0; ; {
+		n := api.nodes.pick()
+
+		var URL *url.URL
+		URL, err = parseNodeURL(n, path)
+		if err != nil {
+			log.Event(ctx, "failed to create url for elastic call", log.ERROR, log.Error(err), logData)
+			return nil, 0, err
+		}
+		logData["url"] = URL.String()
+
+		responseBody, status, err = api.doRequest(requestCtx, method, URL, body, api.policy.timeout(operation), logData)
+		if isConnectionError(requestCtx, status, err) {
+			api.nodes.markDead(ctx, n, err)
+		}
+
+		if !isFailure(status, err) || ctx.Err() != nil {
+			break
+		}
+
+		// the request never reached elasticsearch, so it is safe to send to the next node straight away
+		if isDialError(err) && failovers < api.nodes.size()-1 {
+			failovers++
+			continue
+		}
+
+		if attempt >= maxRetries {
+			break
+		}
+		attempt++
+
+		backoff := api.policy.backoff(attempt)
+		logData["attempt"] = attempt
+		logData["backoff"] = backoff.String()
+
+		if deadline, ok := requestCtx.Deadline(); ok && time.Until(deadline) < backoff {
+			log.Event(ctx, "no time left to retry call to elastic", log.WARN, log.Error(err), logData)
+			break
+		}
+
+		log.Event(ctx, "retrying call to elastic", log.WARN, log.Error(err), logData)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			err = ctx.Err()
+			return nil, status, err
+		}
+	}
+
+	return responseBody, status, err
+}
+
+// parseNodeURL returns the url of the path on the node
+func parseNodeURL(n *node, path string) (*url.URL, error) {
+	return url.Parse(n.url + path)
+}
+
+// doRequest sends a single request to elastic search
+func (api *API) doRequest(ctx context.Context, method string, URL *url.URL, body []byte, timeout time.Duration, logData log.Data) ([]byte, int, error) {
+	var req *http.Request
+	var err error
+
+	if body != nil {
+		req, err = http.NewRequest(method, URL.String(), bytes.NewReader(body))
+		if err == nil {
+			req.Header.Add("Content-type", "application/json")
+		}
 	} else {
-		req, err = http.NewRequest(method, path, nil)
+		req, err = http.NewRequest(method, URL.String(), nil)
 	}
 	// check req, above, didn't error
 	if err != nil {
@@ -184,13 +496,35 @@ func (api *API) CallElastic(ctx context.Context, path, method string, payload in
 		return nil, 0, err
 	}
 
+	switch {
+	case api.auth.Signer != nil:
+		if err = api.auth.Signer.Sign(req, body); err != nil {
+			log.Event(ctx, "failed to sign request for call to elastic", log.ERROR, log.Error(err), logData)
+			return nil, 0, err
+		}
+	case api.auth.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+api.auth.APIKey)
+	case api.auth.Username != "":
+		req.SetBasicAuth(api.auth.Username, api.auth.Password)
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
 	resp, err := api.clienter.Do(ctx, req)
 	if err != nil {
+		api.metrics.Observe(req.Method, URL.Path, 0, time.Since(start))
 		log.Event(ctx, "failed to call elastic", log.ERROR, log.Error(err), logData)
 		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
+	api.metrics.Observe(req.Method, URL.Path, resp.StatusCode, time.Since(start))
+
 	logData["http_code"] = resp.StatusCode
 
 	jsonBody, err := ioutil.ReadAll(resp.Body)
@@ -0,0 +1,202 @@
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestAPI returns an API for a stand-in elasticsearch that responds with each status in
+// turn, repeating the last, along with a count of the requests it received
+func newTestAPI(t *testing.T, policy Policy, statuses ...int) (*API, *int32, func()) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n > len(statuses) {
+			n = len(statuses)
+		}
+		w.WriteHeader(statuses[n-1])
+		w.Write([]byte(`{}`))
+	}))
+
+	cli, err := NewClient(TLSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := NewElasticSearchAPI(cli, []string{srv.URL}, Auth{}, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return api, &calls, srv.Close
+}
+
+func TestCallElasticRetries(t *testing.T) {
+	policy := Policy{MaxRetries: 2, RetryBackoff: time.Millisecond}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		statuses   []int
+		wantStatus int
+		wantErr    bool
+		wantCalls  int32
+	}{
+		{
+			name:       "a search is retried until it succeeds",
+			method:     http.MethodPost,
+			path:       "/index/_search",
+			statuses:   []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			wantStatus: http.StatusOK,
+			wantCalls:  3,
+		},
+		{
+			name:       "a get gives up after the retries",
+			method:     http.MethodGet,
+			path:       "/index/_doc/id",
+			statuses:   []int{http.StatusInternalServerError},
+			wantStatus: http.StatusInternalServerError,
+			wantErr:    true,
+			wantCalls:  3,
+		},
+		{
+			name:       "a write is never retried",
+			method:     http.MethodPut,
+			path:       "/index/_doc/id",
+			statuses:   []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantStatus: http.StatusServiceUnavailable,
+			wantErr:    true,
+			wantCalls:  1,
+		},
+		{
+			name:       "a scroll continuation is never retried",
+			method:     http.MethodPost,
+			path:       "/_search/scroll",
+			statuses:   []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantStatus: http.StatusServiceUnavailable,
+			wantErr:    true,
+			wantCalls:  1,
+		},
+		{
+			name:       "an invalid request is not retried",
+			method:     http.MethodGet,
+			path:       "/index/_doc/id",
+			statuses:   []int{http.StatusNotFound, http.StatusOK},
+			wantStatus: http.StatusNotFound,
+			wantErr:    true,
+			wantCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, calls, closeServer := newTestAPI(t, policy, tt.statuses...)
+			defer closeServer()
+
+			_, status, err := api.CallElastic(context.Background(), tt.path, tt.method, nil)
+
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(calls); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCallElasticTotalTimeout(t *testing.T) {
+	policy := Policy{MaxRetries: 10, RetryBackoff: 20 * time.Millisecond, TotalTimeout: 100 * time.Millisecond}
+
+	api, calls, closeServer := newTestAPI(t, policy, http.StatusServiceUnavailable)
+	defer closeServer()
+
+	start := time.Now()
+	_, status, err := api.CallElastic(context.Background(), "/index/_search", http.MethodPost, nil)
+
+	if took := time.Since(start); took > policy.TotalTimeout {
+		t.Errorf("call took %s, want at most %s", took, policy.TotalTimeout)
+	}
+	if status != http.StatusServiceUnavailable || err == nil {
+		t.Errorf("status = %d, err = %v, want the last failure", status, err)
+	}
+	if got := atomic.LoadInt32(calls); got < 2 || got > 10 {
+		t.Errorf("calls = %d, want the retries that fit in the total timeout", got)
+	}
+}
+
+func TestCallElasticBreaker(t *testing.T) {
+	t.Run("failures open the circuit", func(t *testing.T) {
+		breaker := NewCircuitBreaker(1, time.Minute)
+		api, calls, closeServer := newTestAPI(t, Policy{Breaker: breaker}, http.StatusServiceUnavailable)
+		defer closeServer()
+
+		api.CallElastic(context.Background(), "/index/_search", http.MethodPost, nil)
+		_, _, err := api.CallElastic(context.Background(), "/index/_search", http.MethodPost, nil)
+
+		if err == nil {
+			t.Fatal("expected an error while the circuit is open")
+		}
+		if got := atomic.LoadInt32(calls); got != 1 {
+			t.Errorf("calls = %d, want 1", got)
+		}
+	})
+
+	t.Run("a trial cancelled during the backoff lets another trial through", func(t *testing.T) {
+		breaker := NewCircuitBreaker(1, time.Millisecond)
+		breaker.Failure()
+		time.Sleep(2 * time.Millisecond)
+
+		api, _, closeServer := newTestAPI(t, Policy{MaxRetries: 1, RetryBackoff: time.Minute, Breaker: breaker}, http.StatusServiceUnavailable)
+		defer closeServer()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		if _, _, err := api.CallElastic(ctx, "/index/_search", http.MethodPost, nil); err != context.Canceled {
+			t.Fatalf("err = %v, want %v", err, context.Canceled)
+		}
+
+		if allowed, _ := breaker.Allow(); !allowed {
+			t.Fatal("circuit stuck open after the trial was cancelled")
+		}
+	})
+
+	t.Run("a request cancelled by the caller is not counted as a failure", func(t *testing.T) {
+		breaker := NewCircuitBreaker(1, time.Minute)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			<-req.Context().Done()
+		}))
+		defer srv.Close()
+
+		cli, err := NewClient(TLSConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		api, err := NewElasticSearchAPI(cli, []string{srv.URL}, Auth{}, Policy{Breaker: breaker})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		if _, _, err = api.CallElastic(ctx, "/index/_search", http.MethodPost, nil); err == nil {
+			t.Fatal("expected an error from a cancelled request")
+		}
+
+		if allowed, _ := breaker.Allow(); !allowed {
+			t.Fatal("circuit opened by a cancelled request")
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package elasticsearch
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/config"
+)
+
+// RegisterFlags adds command line flags for the elasticsearch connection settings of
+// the configuration to the flag set, for scripts to override the settings read from
+// the environment. Each flag defaults to the configured value
+func RegisterFlags(fs *flag.FlagSet, cfg *config.Config) {
+	fs.Var((*urlsValue)(&cfg.ElasticSearchAPIURLs), "elasticsearch-url", "the elasticsearch url, or a comma separated list of node urls")
+	fs.StringVar(&cfg.ElasticSearchCACertFile, "ca-cert-file", cfg.ElasticSearchCACertFile, "the CA bundle used to verify the elasticsearch certificate")
+	fs.StringVar(&cfg.ElasticSearchClientCertFile, "client-cert-file", cfg.ElasticSearchClientCertFile, "the client certificate presented to elasticsearch")
+	fs.StringVar(&cfg.ElasticSearchClientKeyFile, "client-key-file", cfg.ElasticSearchClientKeyFile, "the private key of the client certificate")
+	fs.StringVar(&cfg.ElasticSearchUsername, "username", cfg.ElasticSearchUsername, "the username for basic auth against elasticsearch")
+	fs.StringVar(&cfg.ElasticSearchPassword, "password", cfg.ElasticSearchPassword, "the password for basic auth against elasticsearch, prefer setting ELASTIC_SEARCH_PASSWORD")
+	fs.StringVar(&cfg.ElasticSearchAPIKey, "api-key", cfg.ElasticSearchAPIKey, "the api key sent to elasticsearch, prefer setting ELASTIC_SEARCH_API_KEY")
+	fs.BoolVar(&cfg.SignElasticsearchRequests, "sign-requests", cfg.SignElasticsearchRequests, "sign elasticsearch requests with aws sigv4, for use with an aws managed elasticsearch cluster")
+	fs.StringVar(&cfg.AWSRegion, "aws-region", cfg.AWSRegion, "the aws region of the elasticsearch cluster, used when signing requests")
+	fs.StringVar(&cfg.AWSService, "aws-service", cfg.AWSService, "the aws service name, used when signing requests")
+}
+
+// urlsValue is a flag holding a comma separated list of urls
+type urlsValue []string
+
+func (v *urlsValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return strings.Join(*v, ",")
+}
+
+func (v *urlsValue) Set(value string) error {
+	*v = strings.Split(value, ",")
+	return nil
+}
@@ -0,0 +1,182 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/log.go/log"
+)
+
+// node is a single elasticsearch node that requests can be sent to
+type node struct {
+	url       string
+	dead      bool
+	deadSince time.Time
+}
+
+// nodePool balances requests across elasticsearch nodes, skipping nodes that
+// have failed to accept a connection until they respond to a probe again
+type nodePool struct {
+	mutex sync.Mutex
+	nodes []*node
+	next  int
+
+	probeDone chan struct{}
+	probeWG   sync.WaitGroup
+}
+
+// ErrNoNodes is returned when no elasticsearch node urls are configured
+var ErrNoNodes = errors.New("no elasticsearch node urls configured")
+
+// newNodePool creates a pool of the node urls, ignoring surrounding whitespace, a
+// trailing slash and empty urls, such as those left by a trailing comma
+func newNodePool(urls []string) (*nodePool, error) {
+	pool := &nodePool{}
+	for _, url := range urls {
+		url = strings.TrimRight(strings.TrimSpace(url), "/")
+		if url == "" {
+			continue
+		}
+
+		pool.nodes = append(pool.nodes, &node{url: url})
+	}
+
+	if len(pool.nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	return pool, nil
+}
+
+// pick returns the next live node in turn, when every node is dead the next
+// node is returned regardless, so a recovered cluster is found straight away
+func (pool *nodePool) pick() *node {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for i := range pool.nodes {
+		n := pool.nodes[(pool.next+i)%len(pool.nodes)]
+		if !n.dead {
+			pool.next = (pool.next + i + 1) % len(pool.nodes)
+			return n
+		}
+	}
+
+	n := pool.nodes[pool.next]
+	pool.next = (pool.next + 1) % len(pool.nodes)
+
+	return n
+}
+
+func (pool *nodePool) markDead(ctx context.Context, n *node, err error) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if n.dead {
+		return
+	}
+
+	n.dead = true
+	n.deadSince = time.Now()
+
+	log.Event(ctx, "marking elasticsearch node as dead", log.WARN, log.Error(err), log.Data{"node": n.url})
+}
+
+func (pool *nodePool) markAlive(ctx context.Context, n *node) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if !n.dead {
+		return
+	}
+
+	log.Event(ctx, "elasticsearch node is alive again", log.INFO, log.Data{"node": n.url, "dead_for": time.Since(n.deadSince).String()})
+
+	n.dead = false
+}
+
+func (pool *nodePool) deadNodes() []*node {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	var dead []*node
+	for _, n := range pool.nodes {
+		if n.dead {
+			dead = append(dead, n)
+		}
+	}
+
+	return dead
+}
+
+func (pool *nodePool) size() int {
+	return len(pool.nodes)
+}
+
+// StartNodeCheck probes dead nodes on the interval in the background, putting
+// them back into rotation once they respond
+func (api *API) StartNodeCheck(ctx context.Context, interval time.Duration) {
+	pool := api.nodes
+
+	pool.probeDone = make(chan struct{})
+	pool.probeWG.Add(1)
+
+	go func() {
+		defer pool.probeWG.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				for _, n := range pool.deadNodes() {
+					api.probe(ctx, n)
+				}
+			case <-pool.probeDone:
+				return
+			}
+		}
+	}()
+}
+
+// StopNodeCheck stops probing dead nodes in the background
+func (api *API) StopNodeCheck() {
+	if api.nodes.probeDone == nil {
+		return
+	}
+
+	close(api.nodes.probeDone)
+	api.nodes.probeWG.Wait()
+	api.nodes.probeDone = nil
+}
+
+// probe calls the root of a dead node, bypassing the circuit breaker and retries
+func (api *API) probe(ctx context.Context, n *node) {
+	logData := log.Data{"url": n.url, "method": "GET"}
+
+	URL, err := parseNodeURL(n, "/")
+	if err != nil {
+		return
+	}
+
+	if _, _, err := api.doRequest(ctx, "GET", URL, nil, api.policy.GetTimeout, logData); err == nil {
+		api.nodes.markAlive(ctx, n)
+	}
+}
+
+// isConnectionError reports whether the request failed without a response from the node
+func isConnectionError(ctx context.Context, status int, err error) bool {
+	return err != nil && status == 0 && ctx.Err() == nil && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// isDialError reports whether the connection to the node could not be opened,
+// in which case the request never reached elasticsearch
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
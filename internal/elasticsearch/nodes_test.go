@@ -0,0 +1,92 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNewNodePool(t *testing.T) {
+	tests := []struct {
+		name    string
+		urls    []string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "trailing slashes are removed",
+			urls: []string{"http://a:9200/", "http://b:9200"},
+			want: []string{"http://a:9200", "http://b:9200"},
+		},
+		{
+			name: "whitespace is trimmed",
+			urls: []string{"http://a:9200", " http://b:9200", "\thttp://c:9200/ "},
+			want: []string{"http://a:9200", "http://b:9200", "http://c:9200"},
+		},
+		{
+			name: "empty urls are dropped",
+			urls: []string{"http://a:9200", "", " ", "/"},
+			want: []string{"http://a:9200"},
+		},
+		{
+			name:    "no urls",
+			wantErr: ErrNoNodes,
+		},
+		{
+			name:    "only empty urls",
+			urls:    []string{"", " "},
+			wantErr: ErrNoNodes,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := newNodePool(tt.urls)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var urls []string
+			for _, n := range pool.nodes {
+				urls = append(urls, n.url)
+			}
+
+			if !reflect.DeepEqual(urls, tt.want) {
+				t.Errorf("urls = %v, want %v", urls, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallElasticFailover(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	// nothing listens on a closed server, so connections to it are refused
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	cli, err := NewClient(TLSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := NewElasticSearchAPI(cli, []string{dead.URL, " " + srv.URL + "/ "}, Auth{}, Policy{MaxRetries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, status, err := api.CallElastic(context.Background(), "/index/_search", http.MethodPost, nil); err != nil || status != http.StatusOK {
+			t.Errorf("call %d: status = %d, err = %v, want the live node to answer", i+1, status, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package elasticsearch
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A list of the kinds of operation a request to elasticsearch can be
+const (
+	operationSearch = "search"
+	operationScroll = "scroll"
+	operationGet    = "get"
+	operationWrite  = "write"
+)
+
+// Policy holds the timeouts, retries and circuit breaker applied to requests to
+// elasticsearch, a zero timeout means the request has no timeout of its own
+type Policy struct {
+	SearchTimeout time.Duration
+	GetTimeout    time.Duration
+	WriteTimeout  time.Duration
+
+	// MaxRetries is the number of times a failed search or get is retried, writes and
+	// scroll continuations are never retried
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// TotalTimeout bounds a search or get including its retries, a zero timeout means
+	// the retries are only bounded by the timeout of each attempt
+	TotalTimeout time.Duration
+
+	// Breaker is optional, when nil requests are always sent
+	Breaker *CircuitBreaker
+}
+
+// DefaultPolicy returns the timeouts and retries used when none are configured, without a
+// circuit breaker or a total timeout
+func DefaultPolicy() Policy {
+	return Policy{
+		SearchTimeout: 10 * time.Second,
+		GetTimeout:    10 * time.Second,
+		WriteTimeout:  60 * time.Second,
+		MaxRetries:    3,
+		RetryBackoff:  100 * time.Millisecond,
+	}
+}
+
+func (p Policy) timeout(operation string) time.Duration {
+	switch operation {
+	case operationSearch, operationScroll:
+		return p.SearchTimeout
+	case operationGet:
+		return p.GetTimeout
+	default:
+		return p.WriteTimeout
+	}
+}
+
+// backoff returns how long to wait before the retry, doubling on each attempt
+// with jitter so that many clients retrying do not hit elasticsearch together
+func (p Policy) backoff(retry int) time.Duration {
+	backoff := p.RetryBackoff * time.Duration(1<<uint(retry-1))
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// retryable reports whether a failed request of the kind can be sent again
+func retryable(operation string) bool {
+	return operation == operationSearch || operation == operationGet
+}
+
+// operationKind classifies a request, searches and counts are idempotent even
+// when sent as a POST, any other request that is not a GET or HEAD is a write.
+// Requests to the scroll api are not idempotent, as each continuation moves the
+// scroll on, so a retry after a lost response would skip a page
+func operationKind(method, path string) string {
+	if strings.Contains(path, "/_search/scroll") {
+		return operationScroll
+	}
+
+	if strings.Contains(path, "/_search") || strings.Contains(path, "/_count") {
+		return operationSearch
+	}
+
+	if method == http.MethodGet || method == http.MethodHead {
+		return operationGet
+	}
+
+	return operationWrite
+}
+
+// isFailure reports whether the response means elasticsearch is unhealthy, as
+// opposed to the request being invalid, such failures are worth retrying
+func isFailure(status int, err error) bool {
+	if status == 0 {
+		return err != nil
+	}
+
+	return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
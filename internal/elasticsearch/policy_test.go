@@ -0,0 +1,67 @@
+package elasticsearch
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestOperationKind(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodPost, "/index/_search", operationSearch},
+		{http.MethodGet, "/index/_count", operationSearch},
+		{http.MethodPost, "/index/_search?scroll=1m", operationSearch},
+		{http.MethodPost, "/_search/scroll", operationScroll},
+		{http.MethodDelete, "/_search/scroll", operationScroll},
+		{http.MethodGet, "/index/_doc/id", operationGet},
+		{http.MethodHead, "/index", operationGet},
+		{http.MethodPut, "/index/_doc/id", operationWrite},
+		{http.MethodPost, "/_bulk", operationWrite},
+		{http.MethodDelete, "/index", operationWrite},
+	}
+
+	for _, tt := range tests {
+		if got := operationKind(tt.method, tt.path); got != tt.want {
+			t.Errorf("operationKind(%s, %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIsFailure(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		want   bool
+	}{
+		{0, errors.New("connection refused"), true},
+		{0, nil, false},
+		{http.StatusOK, nil, false},
+		{http.StatusNotFound, ErrorUnexpectedStatusCode, false},
+		{http.StatusTooManyRequests, ErrorUnexpectedStatusCode, true},
+		{http.StatusInternalServerError, ErrorUnexpectedStatusCode, true},
+		{http.StatusServiceUnavailable, ErrorUnexpectedStatusCode, true},
+	}
+
+	for _, tt := range tests {
+		if got := isFailure(tt.status, tt.err); got != tt.want {
+			t.Errorf("isFailure(%d, %v) = %t, want %t", tt.status, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{RetryBackoff: 100 * time.Millisecond}
+
+	for retry, max := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		for i := 0; i < 20; i++ {
+			if got := p.backoff(retry + 1); got < max/2 || got > max {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", retry+1, got, max/2, max)
+			}
+		}
+	}
+}
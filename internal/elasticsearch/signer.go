@@ -0,0 +1,46 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// Signer signs requests to elasticsearch with AWS Signature Version 4, so
+// that the service can call an AWS managed elasticsearch cluster
+type Signer struct {
+	region  string
+	service string
+	signer  *v4.Signer
+}
+
+// NewSigner creates a Signer for the region and service, credentials are
+// retrieved from the environment, the shared credentials file or the role
+// of the instance or container the service is running on
+func NewSigner(region, service string) (*Signer, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{
+		region:  region,
+		service: service,
+		signer:  v4.NewSigner(sess.Config.Credentials),
+	}, nil
+}
+
+// Sign adds the authorisation headers to the request, the payload must be the request body
+func (s *Signer) Sign(req *http.Request, payload []byte) error {
+	var body io.ReadSeeker
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	_, err := s.signer.Sign(req, body, s.service, s.region, time.Now())
+	return err
+}
@@ -0,0 +1,74 @@
+package elasticsearch
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// setAWSCredentials sets static aws credentials in the environment for the signer to
+// use, returning a func restoring the previous environment
+func setAWSCredentials(t *testing.T) func() {
+	vars := map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "",
+	}
+
+	previous := make(map[string]string)
+	for key, value := range vars {
+		previous[key] = os.Getenv(key)
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return func() {
+		for key, value := range previous {
+			os.Setenv(key, value)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	defer setAWSCredentials(t)()
+
+	signer, err := NewSigner("eu-west-2", "es")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sign := func(payload []byte) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "https://search.example.com/datasets/_search", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = signer.Sign(req, payload); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return req
+	}
+
+	req := sign([]byte(`{"query":{}}`))
+
+	authorization := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+		!strings.Contains(authorization, "/eu-west-2/es/aws4_request") {
+		t.Errorf("authorization = %q, want a sigv4 signature for the region and service", authorization)
+	}
+	if req.Header.Get("X-Amz-Date") == "" {
+		t.Error("request not dated")
+	}
+
+	// the signature covers the payload
+	if other := sign([]byte(`{"query":{"match_all":{}}}`)).Header.Get("Authorization"); other == authorization {
+		t.Error("different payloads have the same signature")
+	}
+
+	if empty := sign(nil).Header.Get("Authorization"); empty == "" {
+		t.Error("request without a payload not signed")
+	}
+}
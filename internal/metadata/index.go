@@ -0,0 +1,160 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+const (
+	// DimensionsID is the id of the document storing the list of dimensions in the metadata index
+	DimensionsID = "dimensions"
+	// TaxonomyID is the id of the document storing the taxonomy in the metadata index
+	TaxonomyID = "taxonomy"
+	// MappingsFile is the name of the mappings file used to create the metadata index
+	MappingsFile = "metadata-mappings.json"
+)
+
+// Reader is the elasticsearch client used to read metadata documents
+type Reader interface {
+	GetDocument(ctx context.Context, indexName, id string) ([]byte, int, error)
+}
+
+// Writer is the elasticsearch client used to store metadata documents
+type Writer interface {
+	CreateSearchIndex(ctx context.Context, indexName string, mappingsFile string) (int, error)
+	PutDocument(ctx context.Context, indexName, id string, bytes []byte) (int, error)
+}
+
+// ReadTaxonomyIndex reads and validates the taxonomy stored in the metadata index
+func ReadTaxonomyIndex(ctx context.Context, es Reader, indexName string) (*models.Taxonomy, error) {
+	logData := log.Data{"metadata_index": indexName, "id": TaxonomyID}
+
+	b, status, err := es.GetDocument(ctx, indexName, TaxonomyID)
+	if err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to read taxonomy from metadata index", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	var taxonomy models.Taxonomy
+
+	if err = json.Unmarshal(b, &taxonomy); err != nil {
+		log.Event(ctx, "unable to unmarshal taxonomy into struct", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	if err = taxonomy.Validate(); err != nil {
+		log.Event(ctx, "invalid taxonomy", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	return &taxonomy, nil
+}
+
+// ReadDimensionsIndex reads and validates the list of dimensions stored in the metadata index
+func ReadDimensionsIndex(ctx context.Context, es Reader, indexName string) (*models.DimensionsDoc, error) {
+	logData := log.Data{"metadata_index": indexName, "id": DimensionsID}
+
+	b, status, err := es.GetDocument(ctx, indexName, DimensionsID)
+	if err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to read dimensions from metadata index", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	var dimensions models.DimensionsDoc
+
+	if err = json.Unmarshal(b, &dimensions); err != nil {
+		log.Event(ctx, "unable to unmarshal dimensions into struct", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	if err = dimensions.Validate(); err != nil {
+		log.Event(ctx, "invalid dimensions", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	return &dimensions, nil
+}
+
+// LoadTaxonomy reads the taxonomy from the metadata index, falling back to the
+// taxonomy file if the index has not been set or the document cannot be read.
+// It reports whether the taxonomy was read from the file
+func LoadTaxonomy(ctx context.Context, es Reader, indexName, filename string) (*models.Taxonomy, bool, error) {
+	if indexName != "" {
+		taxonomy, err := ReadTaxonomyIndex(ctx, es, indexName)
+		if err == nil {
+			return taxonomy, false, nil
+		}
+
+		log.Event(ctx, "falling back to reading taxonomy from file", log.WARN, log.Error(err), log.Data{"taxonomy_filename": filename})
+	}
+
+	taxonomy, err := ReadTaxonomyFile(ctx, filename)
+	return taxonomy, true, err
+}
+
+// LoadDimensions reads the list of dimensions from the metadata index, falling back to
+// the dimensions file if the index has not been set or the document cannot be read.
+// It reports whether the dimensions were read from the file
+func LoadDimensions(ctx context.Context, es Reader, indexName, filename string) (*models.DimensionsDoc, bool, error) {
+	if indexName != "" {
+		dimensions, err := ReadDimensionsIndex(ctx, es, indexName)
+		if err == nil {
+			return dimensions, false, nil
+		}
+
+		log.Event(ctx, "falling back to reading dimensions from file", log.WARN, log.Error(err), log.Data{"dimensions_filename": filename})
+	}
+
+	dimensions, err := ReadDimensionsFile(ctx, filename)
+	return dimensions, true, err
+}
+
+// WriteIndex stores the taxonomy and list of dimensions in the metadata index,
+// creating the index if it does not already exist
+func WriteIndex(ctx context.Context, es Writer, indexName string, taxonomy models.Taxonomy, dimensions models.DimensionsDoc) error {
+	logData := log.Data{"metadata_index": indexName}
+
+	status, err := es.CreateSearchIndex(ctx, indexName, MappingsFile)
+	if err != nil {
+		// elasticsearch responds with a bad request if the index already exists
+		if status != http.StatusBadRequest {
+			logData["status"] = status
+			log.Event(ctx, "failed to create metadata index", log.ERROR, log.Error(err), logData)
+			return err
+		}
+
+		log.Event(ctx, "metadata index already exists, continuing", log.INFO, logData)
+	}
+
+	taxonomyBytes, err := json.Marshal(taxonomy)
+	if err != nil {
+		log.Event(ctx, "failed to marshal taxonomy to bytes", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	if status, err = es.PutDocument(ctx, indexName, TaxonomyID, taxonomyBytes); err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to store taxonomy in metadata index", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	dimensionsBytes, err := json.Marshal(dimensions)
+	if err != nil {
+		log.Event(ctx, "failed to marshal dimensions to bytes", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	if status, err = es.PutDocument(ctx, indexName, DimensionsID, dimensionsBytes); err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to store dimensions in metadata index", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package metadata
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTaxonomy(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	filename := filepath.Join(dir, "taxonomy.json")
+	writeFile(t, filename, taxonomyJSON("file"))
+
+	tests := []struct {
+		name         string
+		indexName    string
+		documents    map[string]string
+		filename     string
+		wantTopic    string
+		wantFromFile bool
+		wantErr      bool
+	}{
+		{
+			name:      "read from the index",
+			indexName: "metadata",
+			documents: map[string]string{TaxonomyID: taxonomyJSON("index")},
+			filename:  filename,
+			wantTopic: "index",
+		},
+		{
+			name:         "no index set",
+			filename:     filename,
+			wantTopic:    "file",
+			wantFromFile: true,
+		},
+		{
+			name:         "missing document",
+			indexName:    "metadata",
+			filename:     filename,
+			wantTopic:    "file",
+			wantFromFile: true,
+		},
+		{
+			name:         "invalid document",
+			indexName:    "metadata",
+			documents:    map[string]string{TaxonomyID: `{"topics":[{"title":"no filterable title"}]}`},
+			filename:     filename,
+			wantTopic:    "file",
+			wantFromFile: true,
+		},
+		{
+			name:      "missing document and file",
+			indexName: "metadata",
+			filename:  filepath.Join(dir, "missing.json"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taxonomy, fromFile, err := LoadTaxonomy(context.Background(), &readerStub{documents: tt.documents}, tt.indexName, tt.filename)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if taxonomy.Topics[0].FormattedTitle != tt.wantTopic || fromFile != tt.wantFromFile {
+				t.Errorf("topic, from file = %q, %t, want %q, %t", taxonomy.Topics[0].FormattedTitle, fromFile, tt.wantTopic, tt.wantFromFile)
+			}
+		})
+	}
+}
+
+func TestLoadDimensions(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	filename := filepath.Join(dir, "dimensions.json")
+	writeFile(t, filename, dimensionsJSON("file"))
+
+	index := &readerStub{documents: map[string]string{DimensionsID: dimensionsJSON("index")}}
+
+	dimensions, fromFile, err := LoadDimensions(context.Background(), index, "metadata", filename)
+	if err != nil || fromFile || dimensions.Dimensions[0].Name != "index" {
+		t.Errorf("dimensions, from file, err = %+v, %t, %v, want the index version", dimensions, fromFile, err)
+	}
+
+	dimensions, fromFile, err = LoadDimensions(context.Background(), &readerStub{}, "metadata", filename)
+	if err != nil || !fromFile || dimensions.Dimensions[0].Name != "file" {
+		t.Errorf("dimensions, from file, err = %+v, %t, %v, want the file version", dimensions, fromFile, err)
+	}
+}
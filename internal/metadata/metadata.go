@@ -0,0 +1,60 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// ReadTaxonomyFile reads and validates the taxonomy stored in a json file
+func ReadTaxonomyFile(ctx context.Context, filename string) (*models.Taxonomy, error) {
+	logData := log.Data{"taxonomy_filename": filename}
+
+	taxonomyFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Event(ctx, "failed to read taxonomy file", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	var taxonomy models.Taxonomy
+
+	if err = json.Unmarshal(taxonomyFile, &taxonomy); err != nil {
+		log.Event(ctx, "unable to unmarshal taxonomy into struct", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	if err = taxonomy.Validate(); err != nil {
+		log.Event(ctx, "invalid taxonomy", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	return &taxonomy, nil
+}
+
+// ReadDimensionsFile reads and validates the list of dimensions stored in a json file
+func ReadDimensionsFile(ctx context.Context, filename string) (*models.DimensionsDoc, error) {
+	logData := log.Data{"dimensions_filename": filename}
+
+	dimensionsFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Event(ctx, "failed to read dimensions file", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	var dimensions models.DimensionsDoc
+
+	if err = json.Unmarshal(dimensionsFile, &dimensions); err != nil {
+		log.Event(ctx, "unable to unmarshal dimensions into struct", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	if err = dimensions.Validate(); err != nil {
+		log.Event(ctx, "invalid dimensions", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	return &dimensions, nil
+}
@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// Refresher periodically reloads the taxonomy and dimensions from the metadata
+// index, and straight away when the process receives a SIGHUP. The new version is
+// only passed on if the document can be read and is valid, otherwise the old
+// version is kept.
+//
+// The Watcher, when set, watches the files of the documents read from file because
+// the index could not be read on startup. It is run by the refresher, not started on
+// its own, so there is a single SIGHUP handler and a file is never reloaded over the
+// index version, as each file stops being watched once its document is read from the
+// index
+type Refresher struct {
+	Elasticsearch Reader
+	IndexName     string
+	Interval      time.Duration
+	OnDimensions  func(models.DimensionsDoc)
+	OnTaxonomy    func(models.Taxonomy)
+	Watcher       *Watcher
+
+	signals chan os.Signal
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Start begins refreshing the taxonomy and dimensions on the interval
+func (r *Refresher) Start(ctx context.Context) {
+	r.signals = make(chan os.Signal, 1)
+	r.done = make(chan struct{})
+	signal.Notify(r.signals, syscall.SIGHUP)
+
+	if r.Watcher != nil {
+		r.Watcher.recordModTimes()
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+
+		// a nil channel is never ready, so files are only checked when there is a watcher
+		var fileChanges <-chan time.Time
+		if r.Watcher != nil {
+			fileTicker := time.NewTicker(r.Watcher.Interval)
+			defer fileTicker.Stop()
+			fileChanges = fileTicker.C
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+				r.refresh(ctx)
+			case <-fileChanges:
+				r.Watcher.reloadChanged(ctx)
+			case <-r.signals:
+				log.Event(ctx, "sighup received, refreshing taxonomy and dimensions from metadata index", log.INFO)
+				r.refresh(ctx)
+				if r.Watcher != nil {
+					r.Watcher.reload(ctx)
+				}
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing the taxonomy and dimensions
+func (r *Refresher) Stop() {
+	signal.Stop(r.signals)
+	close(r.done)
+	r.wg.Wait()
+}
+
+func (r *Refresher) refresh(ctx context.Context) {
+	logData := log.Data{"metadata_index": r.IndexName}
+
+	if taxonomy, err := ReadTaxonomyIndex(ctx, r.Elasticsearch, r.IndexName); err != nil {
+		log.Event(ctx, "failed to refresh taxonomy, keeping previous version", log.WARN, log.Error(err), logData)
+	} else {
+		r.OnTaxonomy(*taxonomy)
+
+		if r.Watcher != nil && r.Watcher.TaxonomyFilename != "" {
+			log.Event(ctx, "taxonomy read from metadata index, no longer watching file", log.INFO, log.Data{"taxonomy_filename": r.Watcher.TaxonomyFilename})
+			r.Watcher.TaxonomyFilename = ""
+		}
+	}
+
+	if dimensions, err := ReadDimensionsIndex(ctx, r.Elasticsearch, r.IndexName); err != nil {
+		log.Event(ctx, "failed to refresh dimensions, keeping previous version", log.WARN, log.Error(err), logData)
+	} else {
+		r.OnDimensions(*dimensions)
+
+		if r.Watcher != nil && r.Watcher.DimensionsFilename != "" {
+			log.Event(ctx, "dimensions read from metadata index, no longer watching file", log.INFO, log.Data{"dimensions_filename": r.Watcher.DimensionsFilename})
+			r.Watcher.DimensionsFilename = ""
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+// readerStub stands in for the metadata index, returning the document stored
+// against each id or an error for any id without one
+type readerStub struct {
+	mutex     sync.Mutex
+	documents map[string]string
+}
+
+func (s *readerStub) GetDocument(ctx context.Context, indexName, id string) ([]byte, int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	document, ok := s.documents[id]
+	if !ok {
+		return nil, 404, errors.New("document not found")
+	}
+
+	return []byte(document), 200, nil
+}
+
+func (s *readerStub) set(id, document string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.documents == nil {
+		s.documents = make(map[string]string)
+	}
+	s.documents[id] = document
+}
+
+func taxonomyJSON(topic string) string {
+	return fmt.Sprintf(`{"topics":[{"title":%q,"filterable_title":%q}]}`, topic, topic)
+}
+
+func dimensionsJSON(name string) string {
+	return fmt.Sprintf(`{"items":[{"label":%q,"name":%q}],"total_count":1}`, name, name)
+}
+
+// writeFile writes the contents to the file with a modification time later than any
+// set before, so a change is seen whatever the resolution of the file system clock
+func writeFile(t *testing.T, filename, contents string) {
+	if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	modTimes++
+	at := time.Now().Add(time.Duration(modTimes) * time.Second)
+	if err := os.Chtimes(filename, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var modTimes int
+
+// recorder records the latest taxonomy and dimensions passed on
+type recorder struct {
+	mutex      sync.Mutex
+	taxonomy   string
+	dimensions string
+	updates    chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{updates: make(chan struct{}, 10)}
+}
+
+func (r *recorder) onTaxonomy(taxonomy models.Taxonomy) {
+	r.mutex.Lock()
+	r.taxonomy = taxonomy.Topics[0].FormattedTitle
+	r.mutex.Unlock()
+	r.updates <- struct{}{}
+}
+
+func (r *recorder) onDimensions(dimensions models.DimensionsDoc) {
+	r.mutex.Lock()
+	r.dimensions = dimensions.Dimensions[0].Name
+	r.mutex.Unlock()
+	r.updates <- struct{}{}
+}
+
+func (r *recorder) current() (string, string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.taxonomy, r.dimensions
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestRefresherWatchesFallbackFiles(t *testing.T) {
+	ctx := context.Background()
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	taxonomyFilename := filepath.Join(dir, "taxonomy.json")
+	dimensionsFilename := filepath.Join(dir, "dimensions.json")
+	writeFile(t, taxonomyFilename, taxonomyJSON("file"))
+	writeFile(t, dimensionsFilename, dimensionsJSON("file"))
+
+	// only the dimensions can be read from the index
+	index := &readerStub{}
+	index.set(DimensionsID, dimensionsJSON("index"))
+
+	got := newRecorder()
+	r := &Refresher{
+		Elasticsearch: index,
+		IndexName:     "metadata",
+		OnDimensions:  got.onDimensions,
+		OnTaxonomy:    got.onTaxonomy,
+		Watcher: &Watcher{
+			TaxonomyFilename: taxonomyFilename,
+			OnDimensions:     got.onDimensions,
+			OnTaxonomy:       got.onTaxonomy,
+		},
+	}
+	r.Watcher.recordModTimes()
+
+	// a change to a file whose document was read from the index is ignored
+	writeFile(t, dimensionsFilename, dimensionsJSON("file-changed"))
+	writeFile(t, taxonomyFilename, taxonomyJSON("file-changed"))
+	r.Watcher.reloadChanged(ctx)
+
+	if taxonomy, dimensions := got.current(); taxonomy != "file-changed" || dimensions != "" {
+		t.Fatalf("taxonomy, dimensions = %q, %q, want only the fallback taxonomy file reloaded", taxonomy, dimensions)
+	}
+
+	// once the taxonomy can be read from the index its file is no longer watched
+	index.set(TaxonomyID, taxonomyJSON("index"))
+	r.refresh(ctx)
+
+	if r.Watcher.TaxonomyFilename != "" {
+		t.Error("taxonomy file still watched after reading the taxonomy from the index")
+	}
+
+	writeFile(t, taxonomyFilename, taxonomyJSON("file-stale"))
+	r.Watcher.reloadChanged(ctx)
+	r.Watcher.reload(ctx)
+
+	if taxonomy, dimensions := got.current(); taxonomy != "index" || dimensions != "index" {
+		t.Errorf("taxonomy, dimensions = %q, %q, want the index versions", taxonomy, dimensions)
+	}
+}
+
+func TestRefresherKeepsPreviousVersion(t *testing.T) {
+	got := newRecorder()
+	r := &Refresher{
+		Elasticsearch: &readerStub{documents: map[string]string{TaxonomyID: `{"topics":[]}`, DimensionsID: `not json`}},
+		IndexName:     "metadata",
+		OnDimensions:  got.onDimensions,
+		OnTaxonomy:    got.onTaxonomy,
+	}
+
+	r.refresh(context.Background())
+
+	if taxonomy, dimensions := got.current(); taxonomy != "" || dimensions != "" {
+		t.Errorf("taxonomy, dimensions = %q, %q, want invalid documents ignored", taxonomy, dimensions)
+	}
+}
+
+func TestRefresherSighup(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	taxonomyFilename := filepath.Join(dir, "taxonomy.json")
+	writeFile(t, taxonomyFilename, taxonomyJSON("file"))
+
+	index := &readerStub{}
+	index.set(DimensionsID, dimensionsJSON("index"))
+
+	got := newRecorder()
+	r := &Refresher{
+		Elasticsearch: index,
+		IndexName:     "metadata",
+		Interval:      time.Hour,
+		OnDimensions:  got.onDimensions,
+		OnTaxonomy:    got.onTaxonomy,
+		Watcher: &Watcher{
+			TaxonomyFilename: taxonomyFilename,
+			Interval:         time.Hour,
+			OnDimensions:     got.onDimensions,
+			OnTaxonomy:       got.onTaxonomy,
+		},
+	}
+
+	r.Start(context.Background())
+	defer r.Stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	// the dimensions are refreshed from the index and the taxonomy reloaded from its file
+	for i := 0; i < 2; i++ {
+		select {
+		case <-got.updates:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the sighup to be handled")
+		}
+	}
+
+	if taxonomy, dimensions := got.current(); taxonomy != "file" || dimensions != "index" {
+		t.Errorf("taxonomy, dimensions = %q, %q, want the file taxonomy and index dimensions", taxonomy, dimensions)
+	}
+}
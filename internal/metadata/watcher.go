@@ -0,0 +1,139 @@
+package metadata
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// Watcher reloads the taxonomy and dimensions files when either file changes
+// on disk or the process receives a SIGHUP, the new version is only passed on
+// if the file can be read and is valid, otherwise the old version is kept. A
+// file is only watched when its filename is set
+type Watcher struct {
+	DimensionsFilename string
+	TaxonomyFilename   string
+	Interval           time.Duration
+	OnDimensions       func(models.DimensionsDoc)
+	OnTaxonomy         func(models.Taxonomy)
+
+	dimensionsModTime time.Time
+	taxonomyModTime   time.Time
+	signals           chan os.Signal
+	done              chan struct{}
+	wg                sync.WaitGroup
+}
+
+// Start records the current state of the files and begins watching them for changes,
+// a watcher run by a Refresher is not started on its own
+func (w *Watcher) Start(ctx context.Context) {
+	w.recordModTimes()
+
+	w.signals = make(chan os.Signal, 1)
+	w.done = make(chan struct{})
+	signal.Notify(w.signals, syscall.SIGHUP)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				w.reloadChanged(ctx)
+			case <-w.signals:
+				log.Event(ctx, "sighup received, reloading taxonomy and dimensions", log.INFO)
+				w.reload(ctx)
+			case <-w.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops watching the files
+func (w *Watcher) Stop() {
+	signal.Stop(w.signals)
+	close(w.done)
+	w.wg.Wait()
+}
+
+func (w *Watcher) recordModTimes() {
+	w.dimensionsModTime = modTime(w.DimensionsFilename)
+	w.taxonomyModTime = modTime(w.TaxonomyFilename)
+}
+
+func (w *Watcher) reload(ctx context.Context) {
+	if w.TaxonomyFilename != "" {
+		w.reloadTaxonomy(ctx)
+	}
+
+	if w.DimensionsFilename != "" {
+		w.reloadDimensions(ctx)
+	}
+}
+
+func (w *Watcher) reloadChanged(ctx context.Context) {
+	if w.TaxonomyFilename != "" {
+		if t := modTime(w.TaxonomyFilename); !t.Equal(w.taxonomyModTime) {
+			w.taxonomyModTime = t
+			w.reloadTaxonomy(ctx)
+		}
+	}
+
+	if w.DimensionsFilename != "" {
+		if t := modTime(w.DimensionsFilename); !t.Equal(w.dimensionsModTime) {
+			w.dimensionsModTime = t
+			w.reloadDimensions(ctx)
+		}
+	}
+}
+
+func (w *Watcher) reloadTaxonomy(ctx context.Context) {
+	logData := log.Data{"taxonomy_filename": w.TaxonomyFilename}
+
+	taxonomy, err := ReadTaxonomyFile(ctx, w.TaxonomyFilename)
+	if err != nil {
+		log.Event(ctx, "failed to reload taxonomy, keeping previous version", log.WARN, log.Error(err), logData)
+		return
+	}
+
+	w.OnTaxonomy(*taxonomy)
+	log.Event(ctx, "successfully reloaded taxonomy", log.INFO, logData)
+}
+
+func (w *Watcher) reloadDimensions(ctx context.Context) {
+	logData := log.Data{"dimensions_filename": w.DimensionsFilename}
+
+	dimensions, err := ReadDimensionsFile(ctx, w.DimensionsFilename)
+	if err != nil {
+		log.Event(ctx, "failed to reload dimensions, keeping previous version", log.WARN, log.Error(err), logData)
+		return
+	}
+
+	w.OnDimensions(*dimensions)
+	log.Event(ctx, "successfully reloaded dimensions", log.INFO, logData)
+}
+
+// modTime returns the last time the file was modified or the zero time if it cannot be read
+func modTime(filename string) time.Time {
+	if filename == "" {
+		return time.Time{}
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
@@ -0,0 +1,75 @@
+package metadata
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatcherReloadChanged(t *testing.T) {
+	ctx := context.Background()
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	taxonomyFilename := filepath.Join(dir, "taxonomy.json")
+	dimensionsFilename := filepath.Join(dir, "dimensions.json")
+	writeFile(t, taxonomyFilename, taxonomyJSON("first"))
+	writeFile(t, dimensionsFilename, dimensionsJSON("first"))
+
+	got := newRecorder()
+	w := &Watcher{
+		DimensionsFilename: dimensionsFilename,
+		TaxonomyFilename:   taxonomyFilename,
+		OnDimensions:       got.onDimensions,
+		OnTaxonomy:         got.onTaxonomy,
+	}
+	w.recordModTimes()
+
+	// nothing is reloaded until a file changes
+	w.reloadChanged(ctx)
+	if taxonomy, dimensions := got.current(); taxonomy != "" || dimensions != "" {
+		t.Fatalf("taxonomy, dimensions = %q, %q, want nothing reloaded", taxonomy, dimensions)
+	}
+
+	writeFile(t, taxonomyFilename, taxonomyJSON("second"))
+	w.reloadChanged(ctx)
+	if taxonomy, dimensions := got.current(); taxonomy != "second" || dimensions != "" {
+		t.Fatalf("taxonomy, dimensions = %q, %q, want only the taxonomy reloaded", taxonomy, dimensions)
+	}
+
+	// an invalid file keeps the previous version
+	writeFile(t, taxonomyFilename, `{"topics":[]}`)
+	writeFile(t, dimensionsFilename, `not json`)
+	w.reloadChanged(ctx)
+	if taxonomy, dimensions := got.current(); taxonomy != "second" || dimensions != "" {
+		t.Fatalf("taxonomy, dimensions = %q, %q, want the previous versions kept", taxonomy, dimensions)
+	}
+
+	writeFile(t, dimensionsFilename, dimensionsJSON("second"))
+	w.reloadChanged(ctx)
+	if taxonomy, dimensions := got.current(); taxonomy != "second" || dimensions != "second" {
+		t.Errorf("taxonomy, dimensions = %q, %q, want the dimensions reloaded", taxonomy, dimensions)
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	dimensionsFilename := filepath.Join(dir, "dimensions.json")
+	writeFile(t, dimensionsFilename, dimensionsJSON("file"))
+
+	// the taxonomy file is not watched, so is never read
+	got := newRecorder()
+	w := &Watcher{
+		DimensionsFilename: dimensionsFilename,
+		OnDimensions:       got.onDimensions,
+		OnTaxonomy:         got.onTaxonomy,
+	}
+
+	w.reload(context.Background())
+
+	if taxonomy, dimensions := got.current(); taxonomy != "" || dimensions != "file" {
+		t.Errorf("taxonomy, dimensions = %q, %q, want only the dimensions reloaded", taxonomy, dimensions)
+	}
+}
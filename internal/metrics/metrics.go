@@ -0,0 +1,216 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dataset_search"
+
+// API holds the metrics of a single search api, a nil API records nothing
+type API struct {
+	httpRequests       *prometheus.CounterVec
+	httpDuration       *prometheus.HistogramVec
+	zeroResultSearches prometheus.Counter
+	taxonomyTopics     prometheus.Gauge
+	dimensions         prometheus.Gauge
+}
+
+// NewAPI creates the metrics of a search api and registers them with the registerer,
+// each search api in a process needs a registerer of its own
+func NewAPI(reg prometheus.Registerer) (*API, error) {
+	m := &API{
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "The number of http requests handled, by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "The time taken to handle http requests, by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+
+		zeroResultSearches: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "zero_result_searches_total",
+			Help:      "The number of dataset searches that returned no results.",
+		}),
+
+		taxonomyTopics: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "taxonomy_topics",
+			Help:      "The number of topics across all levels of the loaded taxonomy.",
+		}),
+
+		dimensions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "dimensions",
+			Help:      "The number of loaded dimensions.",
+		}),
+	}
+
+	if err := register(reg, m.httpRequests, m.httpDuration, m.zeroResultSearches, m.taxonomyTopics, m.dimensions); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Middleware records the number and duration of requests against each route,
+// using the route template so that path variables do not create new series
+func (m *API) Middleware(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		status := strconv.Itoa(recorder.status)
+		m.httpRequests.WithLabelValues(route, r.Method, status).Inc()
+		m.httpDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// IncZeroResultSearches records a dataset search that returned no results
+func (m *API) IncZeroResultSearches() {
+	if m == nil {
+		return
+	}
+
+	m.zeroResultSearches.Inc()
+}
+
+// SetTaxonomyTopics records the number of topics in the loaded taxonomy
+func (m *API) SetTaxonomyTopics(count int) {
+	if m == nil {
+		return
+	}
+
+	m.taxonomyTopics.Set(float64(count))
+}
+
+// SetDimensions records the number of loaded dimensions
+func (m *API) SetDimensions(count int) {
+	if m == nil {
+		return
+	}
+
+	m.dimensions.Set(float64(count))
+}
+
+// Elasticsearch holds the metrics of a single elasticsearch client, a nil Elasticsearch
+// records nothing
+type Elasticsearch struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewElasticsearch creates the metrics of an elasticsearch client and registers them
+// with the registerer
+func NewElasticsearch(reg prometheus.Registerer) (*Elasticsearch, error) {
+	m := &Elasticsearch{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "elasticsearch_requests_total",
+			Help:      "The number of requests made to elasticsearch, by operation and status code.",
+		}, []string{"operation", "status"}),
+
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "elasticsearch_request_duration_seconds",
+			Help:      "The time taken for elasticsearch to respond, by operation and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "status"}),
+	}
+
+	if err := register(reg, m.requests, m.duration); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Observe records a request made to elasticsearch, a status of 0 means no response
+// was received
+func (m *Elasticsearch) Observe(method, path string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	operation := Operation(method, path)
+	statusLabel := strconv.Itoa(status)
+
+	m.requests.WithLabelValues(operation, statusLabel).Inc()
+	m.duration.WithLabelValues(operation, statusLabel).Observe(duration.Seconds())
+}
+
+// Operation names the elasticsearch api being called from the method and the
+// first path segment beginning with an underscore, e.g. "GET _search", paths
+// without an api segment operate on the index itself, e.g. "PUT index"
+func Operation(method, path string) string {
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "_") {
+			return method + " " + segment
+		}
+	}
+
+	return method + " index"
+}
+
+// NewRegistry creates a registry holding the go runtime and process metrics, for the
+// metrics of the search api and elasticsearch client to be registered with
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	return reg
+}
+
+// Handler returns the http handler exposing the metrics gathered by the gatherer in the
+// prometheus format
+func Handler(gatherer prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+}
+
+func register(reg prometheus.Registerer, collectors ...prometheus.Collector) error {
+	for _, collector := range collectors {
+		if err := reg.Register(collector); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewAPI(t *testing.T) {
+	first, err := NewAPI(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := NewAPI(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first.SetDimensions(3)
+	second.SetDimensions(5)
+
+	if got := testutil.ToFloat64(first.dimensions); got != 3 {
+		t.Errorf("first dimensions = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(second.dimensions); got != 5 {
+		t.Errorf("second dimensions = %v, want 5", got)
+	}
+
+	reg := prometheus.NewRegistry()
+	if _, err = NewAPI(reg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = NewAPI(reg); err == nil {
+		t.Error("expected an error registering two apis with one registerer")
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var api *API
+	api.SetDimensions(1)
+	api.SetTaxonomyTopics(1)
+	api.IncZeroResultSearches()
+
+	var es *Elasticsearch
+	es.Observe("GET", "/_search", 200, 0)
+}
+
+func TestOperation(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"POST", "/index/_search", "POST _search"},
+		{"GET", "/index/_doc/id", "GET _doc"},
+		{"PUT", "/index", "PUT index"},
+		{"GET", "/", "GET index"},
+	}
+
+	for _, tt := range tests {
+		if got := Operation(tt.method, tt.path); got != tt.want {
+			t.Errorf("Operation(%s, %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	m, err := NewAPI(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.Use(m.Middleware)
+	router.HandleFunc("/taxonomy/{topic}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["topic"] == "unknown" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	for _, target := range []string{"/taxonomy/economy", "/taxonomy/people", "/taxonomy/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	// path variables are recorded against the route template, not the path
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("/taxonomy/{topic}", "GET", "200")); got != 2 {
+		t.Errorf("ok requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("/taxonomy/{topic}", "GET", "404")); got != 1 {
+		t.Errorf("not found requests = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.httpRequests); got != 2 {
+		t.Errorf("request series = %d, want 2", got)
+	}
+	if got := testutil.CollectAndCount(m.httpDuration); got != 2 {
+		t.Errorf("duration series = %d, want 2", got)
+	}
+}
+
+func TestNilMiddleware(t *testing.T) {
+	var m *API
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	m.Middleware(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("code = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := NewElasticsearch(reg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m.Observe("POST", "/datasets/_search", 200, time.Second)
+	m.Observe("POST", "/datasets/_search", 200, time.Second)
+	m.Observe("GET", "/_cluster/health", 0, time.Second)
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("POST _search", "200")); got != 2 {
+		t.Errorf("search requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("GET _cluster", "0")); got != 1 {
+		t.Errorf("failed requests = %v, want 1", got)
+	}
+
+	w := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.Contains(w.Body.String(), `dataset_search_elasticsearch_requests_total{operation="POST _search",status="200"} 2`) {
+		t.Errorf("metrics = %s, want the search requests exposed", w.Body.String())
+	}
+}
@@ -0,0 +1,87 @@
+package taxonomy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// responseCache stores the body of each successful response from the ons website in a
+// directory, so crawls repeated within the ttl do not request every page again
+type responseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// newResponseCache creates a cache in the directory, a cache without a directory or
+// with a ttl of zero stores nothing
+func newResponseCache(dir string, ttl time.Duration) (*responseCache, error) {
+	if dir == "" || ttl <= 0 {
+		return &responseCache{}, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &responseCache{dir: dir, ttl: ttl}, nil
+}
+
+func (c *responseCache) enabled() bool {
+	return c.dir != ""
+}
+
+func (c *responseCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the cached body for the url, if it was stored within the ttl
+func (c *responseCache) get(url string) ([]byte, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+
+	path := c.path(url)
+
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return nil, false
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	return b, true
+}
+
+// put stores the body for the url, writing to a temporary file first so concurrent
+// crawls never read a partly written body
+func (c *responseCache) put(url string, body []byte) error {
+	if !c.enabled() {
+		return nil
+	}
+
+	file, err := ioutil.TempFile(c.dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(body); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), c.path(url))
+}
@@ -0,0 +1,79 @@
+package taxonomy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "taxonomy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestResponseCache(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	const url = "https://www.ons.gov.uk/economy/data"
+
+	tests := []struct {
+		name   string
+		dir    string
+		ttl    time.Duration
+		age    time.Duration
+		wantOK bool
+	}{
+		{name: "within the ttl", dir: filepath.Join(dir, "fresh"), ttl: time.Hour, age: time.Minute, wantOK: true},
+		{name: "older than the ttl", dir: filepath.Join(dir, "stale"), ttl: time.Hour, age: 2 * time.Hour},
+		{name: "a ttl of zero disables the cache", dir: filepath.Join(dir, "disabled"), ttl: 0},
+		{name: "no directory disables the cache", ttl: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, err := newResponseCache(tt.dir, tt.ttl)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err = cache.put(url, []byte(`{"uri":"/economy"}`)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if cache.enabled() {
+				storedAt := time.Now().Add(-tt.age)
+				if err = os.Chtimes(cache.path(url), storedAt, storedAt); err != nil {
+					t.Fatal(err)
+				}
+
+				// the body is written to a temporary file first, which is never left behind
+				files, err := ioutil.ReadDir(tt.dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(files) != 1 || files[0].Name() != filepath.Base(cache.path(url)) {
+					t.Errorf("cache directory holds %d files, want only the cached body", len(files))
+				}
+			}
+
+			body, ok := cache.get(url)
+			if ok != tt.wantOK {
+				t.Fatalf("cached = %t, want %t", ok, tt.wantOK)
+			}
+			if ok && string(body) != `{"uri":"/economy"}` {
+				t.Errorf("body = %s, want the stored body", body)
+			}
+
+			if _, ok = cache.get("https://www.ons.gov.uk/people/data"); ok {
+				t.Error("found a body for a url that was never stored")
+			}
+		})
+	}
+}
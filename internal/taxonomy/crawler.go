@@ -0,0 +1,195 @@
+package taxonomy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// crawler walks the taxonomy of the ons website, requesting pages from a number of
+// workers at the same time while keeping topics in the order they appear on each page
+type crawler struct {
+	cache  *responseCache
+	client *http.Client
+	slots  chan struct{}
+	url    string
+}
+
+// newCrawler creates a crawler for the website at the url, sending at most workers
+// requests at the same time
+func newCrawler(url string, workers int, timeout time.Duration, cache *responseCache) *crawler {
+	return &crawler{
+		cache:  cache,
+		client: &http.Client{Timeout: timeout},
+		slots:  make(chan struct{}, workers),
+		url:    url,
+	}
+}
+
+// crawl retrieves the taxonomy, starting from the top level topics on the home page
+func (c *crawler) crawl(ctx context.Context) (*models.Taxonomy, error) {
+	body, err := c.fetch(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if body == nil {
+		return nil, errors.New("home page not found on ons website")
+	}
+
+	topTaxonomy, err := CreateFirstLevelTaxonomy(ctx, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	// handle duplicate top level topics
+	topLevelTopics := make(map[string]bool)
+
+	var uris []string
+	for _, section := range topTaxonomy.Sections {
+		if !topLevelTopics[section.Theme.URI] {
+			topLevelTopics[section.Theme.URI] = true
+			uris = append(uris, section.Theme.URI)
+		}
+	}
+
+	topics, err := c.getTopics(ctx, uris)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Taxonomy{Topics: topics}, nil
+}
+
+// getTopics retrieves each topic at the same time, returning the topics in the order
+// of the uris and skipping any topic that was not found
+func (c *crawler) getTopics(ctx context.Context, uris []string) ([]models.Topic, error) {
+	results := make([]*models.Topic, len(uris))
+	errs := make([]error, len(uris))
+
+	var wg sync.WaitGroup
+	for i, uri := range uris {
+		wg.Add(1)
+		go func(i int, uri string) {
+			defer wg.Done()
+			results[i], errs[i] = c.getTopic(ctx, uri)
+		}(i, uri)
+	}
+	wg.Wait()
+
+	var topics []models.Topic
+	for i := range uris {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+
+		if results[i] == nil {
+			continue
+		}
+
+		topics = append(topics, *results[i])
+	}
+
+	return topics, nil
+}
+
+// getTopic retrieves a topic and, if it is a landing page, all of its child topics
+func (c *crawler) getTopic(ctx context.Context, uri string) (*models.Topic, error) {
+	logData := log.Data{"uri": uri}
+
+	body, err := c.fetch(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if body == nil {
+		log.Event(ctx, "got a not found page", log.WARN, logData)
+		return nil, nil
+	}
+
+	var childTaxonomy ChildTaxonomy
+	if err = json.Unmarshal(body, &childTaxonomy); err != nil {
+		logData["bytes"] = string(body)
+		log.Event(ctx, "getTopic: unable to marshal response data into child taxonomy", log.ERROR, log.Error(err), logData)
+		return nil, errors.New("failed to parse json body")
+	}
+
+	var topics []models.Topic
+	if childTaxonomy.Type == taxonomyLandingPage {
+		uris := make([]string, len(childTaxonomy.Sections))
+		for i, section := range childTaxonomy.Sections {
+			uris[i] = section.URI
+		}
+
+		if topics, err = c.getTopics(ctx, uris); err != nil {
+			return nil, err
+		}
+	}
+
+	title := strings.SplitAfter(uri, "/")
+	formattedTitle := title[len(title)-1]
+
+	return &models.Topic{
+		Title:          childTaxonomy.Description.Title,
+		FormattedTitle: formattedTitle,
+		ChildTopics:    topics,
+	}, nil
+}
+
+// fetch returns the body of the data page for the uri, from the cache if it holds the
+// page, or nil if the page was not found
+func (c *crawler) fetch(ctx context.Context, uri string) ([]byte, error) {
+	pageURL := c.url + uri + "/data"
+	logData := log.Data{"url": pageURL}
+
+	if body, ok := c.cache.get(pageURL); ok {
+		return body, nil
+	}
+
+	c.slots <- struct{}{}
+	defer func() { <-c.slots }()
+
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		log.Event(ctx, "fetch: unsuccessful request", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected status code from ons website: %d", resp.StatusCode)
+		log.Event(ctx, "fetch: unsuccessful request", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Event(ctx, "fetch: failed to read response body", log.ERROR, log.Error(err), logData)
+		return nil, errors.New("unable to read bytes")
+	}
+
+	if err = c.cache.put(pageURL, body); err != nil {
+		log.Event(ctx, "fetch: failed to cache response body", log.WARN, log.Error(err), logData)
+	}
+
+	return body, nil
+}
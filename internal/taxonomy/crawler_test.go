@@ -0,0 +1,166 @@
+package taxonomy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+// websiteStub stands in for the data pages of the ons website, recording the requests it
+// receives and the most requests it was handling at the same time
+type websiteStub struct {
+	pages map[string]string
+	delay time.Duration
+
+	mutex       sync.Mutex
+	requests    int
+	inFlight    int
+	maxInFlight int
+}
+
+func (s *websiteStub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mutex.Lock()
+	s.requests++
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		s.inFlight--
+		s.mutex.Unlock()
+	}()
+
+	time.Sleep(s.delay)
+
+	page, ok := s.pages[strings.TrimSuffix(req.URL.Path, "/data")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	fmt.Fprint(w, page)
+}
+
+func landingPage(title string, uris ...string) string {
+	sections := make([]string, len(uris))
+	for i, uri := range uris {
+		sections[i] = fmt.Sprintf(`{"uri":%q}`, uri)
+	}
+
+	return fmt.Sprintf(`{"type":"taxonomy_landing_page","description":{"title":%q},"sections":[%s]}`, title, strings.Join(sections, ","))
+}
+
+func productPage(title string) string {
+	return fmt.Sprintf(`{"type":"product_page","description":{"title":%q}}`, title)
+}
+
+var testPages = map[string]string{
+	"":                   `{"sections":[{"theme":{"uri":"/economy"}},{"theme":{"uri":"/people"}},{"theme":{"uri":"/economy"}},{"theme":{"uri":"/missing"}}]}`,
+	"/economy":           landingPage("Economy", "/economy/inflation", "/economy/gdp", "/economy/trade", "/economy/missing"),
+	"/people":            landingPage("People", "/people/health", "/people/housing"),
+	"/economy/inflation": productPage("Inflation"),
+	"/economy/gdp":       productPage("GDP"),
+	"/economy/trade":     productPage("Trade"),
+	"/people/health":     productPage("Health"),
+	"/people/housing":    productPage("Housing"),
+}
+
+func TestCrawl(t *testing.T) {
+	stub := &websiteStub{pages: testPages, delay: 20 * time.Millisecond}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	cache, err := newResponseCache("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taxonomy, err := newCrawler(srv.URL, 2, time.Second, cache).crawl(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// topics keep the order of each page, with duplicate and missing topics skipped
+	want := &models.Taxonomy{Topics: []models.Topic{
+		topic("economy", "Economy", topic("inflation", "Inflation"), topic("gdp", "GDP"), topic("trade", "Trade")),
+		topic("people", "People", topic("health", "Health"), topic("housing", "Housing")),
+	}}
+	if !reflect.DeepEqual(taxonomy, want) {
+		t.Errorf("taxonomy = %+v, want %+v", taxonomy, want)
+	}
+
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	if stub.maxInFlight != 2 {
+		t.Errorf("most requests at the same time = %d, want the 2 workers", stub.maxInFlight)
+	}
+}
+
+func TestCrawlCache(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	stub := &websiteStub{pages: testPages}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	cache, err := newResponseCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := newCrawler(srv.URL, 4, time.Second, cache).crawl(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stub.mutex.Lock()
+	requests := stub.requests
+	stub.mutex.Unlock()
+
+	// only pages that were not found are requested again
+	second, err := newCrawler(srv.URL, 4, time.Second, cache).crawl(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	if got := stub.requests - requests; got != 2 {
+		t.Errorf("second crawl sent %d requests, want 2 for the missing pages", got)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("cached taxonomy = %+v, want %+v", second, first)
+	}
+}
+
+func TestCrawlErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/data" {
+			fmt.Fprint(w, `{"sections":[{"theme":{"uri":"/economy"}}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cache, err := newResponseCache("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = newCrawler(srv.URL, 2, time.Second, cache).crawl(context.Background()); err == nil {
+		t.Error("expected an error when a topic page fails")
+	}
+}
@@ -0,0 +1,135 @@
+package taxonomy
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+// topicEntry is a topic along with the path of its parent topics in the taxonomy
+type topicEntry struct {
+	Title  string
+	Parent string
+}
+
+// topicChange is a topic that differs between two taxonomies, topics that were added
+// have no old entry and topics that were removed have no new entry
+type topicChange struct {
+	Topic string
+	Old   topicEntry
+	New   topicEntry
+}
+
+// taxonomyDiff holds the topics that changed between two taxonomies, a topic can be
+// both renamed and moved
+type taxonomyDiff struct {
+	Added     []topicChange
+	Removed   []topicChange
+	Renamed   []topicChange
+	Moved     []topicChange
+	Unchanged int
+}
+
+func (diff taxonomyDiff) changes() int {
+	return len(diff.Added) + len(diff.Removed) + len(diff.Renamed) + len(diff.Moved)
+}
+
+// diffTaxonomies compares the topics in each taxonomy by their filterable title, the
+// changes are sorted by topic
+func diffTaxonomies(oldTaxonomy, newTaxonomy models.Taxonomy) taxonomyDiff {
+	oldTopics := make(map[string]topicEntry)
+	flattenTopics(oldTaxonomy.Topics, nil, oldTopics)
+
+	newTopics := make(map[string]topicEntry)
+	flattenTopics(newTaxonomy.Topics, nil, newTopics)
+
+	var diff taxonomyDiff
+	for _, topic := range sortedTopics(newTopics) {
+		newEntry := newTopics[topic]
+
+		oldEntry, ok := oldTopics[topic]
+		if !ok {
+			diff.Added = append(diff.Added, topicChange{Topic: topic, New: newEntry})
+			continue
+		}
+
+		change := topicChange{Topic: topic, Old: oldEntry, New: newEntry}
+		if oldEntry.Title != newEntry.Title {
+			diff.Renamed = append(diff.Renamed, change)
+		}
+
+		if oldEntry.Parent != newEntry.Parent {
+			diff.Moved = append(diff.Moved, change)
+		}
+
+		if oldEntry == newEntry {
+			diff.Unchanged++
+		}
+	}
+
+	for _, topic := range sortedTopics(oldTopics) {
+		if _, ok := newTopics[topic]; !ok {
+			diff.Removed = append(diff.Removed, topicChange{Topic: topic, Old: oldTopics[topic]})
+		}
+	}
+
+	return diff
+}
+
+// flattenTopics stores every topic, at any depth, against its filterable title
+func flattenTopics(topics []models.Topic, parentPath []string, entries map[string]topicEntry) {
+	for _, topic := range topics {
+		entries[topic.FormattedTitle] = topicEntry{
+			Title:  topic.Title,
+			Parent: strings.Join(parentPath, models.TopicPathSeparator),
+		}
+
+		path := make([]string, len(parentPath), len(parentPath)+1)
+		copy(path, parentPath)
+		path = append(path, topic.FormattedTitle)
+
+		flattenTopics(topic.ChildTopics, path, entries)
+	}
+}
+
+func sortedTopics(entries map[string]topicEntry) []string {
+	topics := make([]string, 0, len(entries))
+	for topic := range entries {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	return topics
+}
+
+// printDiff writes a line for each changed topic followed by a count of each change
+func printDiff(w io.Writer, diff taxonomyDiff) {
+	for _, change := range diff.Added {
+		fmt.Fprintf(w, "+ %s\t%s\n", change.Topic, change.New.Title)
+	}
+
+	for _, change := range diff.Removed {
+		fmt.Fprintf(w, "- %s\t%s\n", change.Topic, change.Old.Title)
+	}
+
+	for _, change := range diff.Renamed {
+		fmt.Fprintf(w, "~ %s\t%s -> %s\n", change.Topic, change.Old.Title, change.New.Title)
+	}
+
+	for _, change := range diff.Moved {
+		fmt.Fprintf(w, "> %s\t%s -> %s\n", change.Topic, parentName(change.Old.Parent), parentName(change.New.Parent))
+	}
+
+	fmt.Fprintf(w, "added: %d, removed: %d, renamed: %d, moved: %d, unchanged: %d\n", len(diff.Added), len(diff.Removed), len(diff.Renamed), len(diff.Moved), diff.Unchanged)
+}
+
+func parentName(parent string) string {
+	if parent == "" {
+		return "(top level)"
+	}
+
+	return parent
+}
@@ -0,0 +1,139 @@
+package taxonomy
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+func topic(formattedTitle, title string, children ...models.Topic) models.Topic {
+	return models.Topic{Title: title, FormattedTitle: formattedTitle, ChildTopics: children}
+}
+
+func TestDiffTaxonomies(t *testing.T) {
+	old := models.Taxonomy{Topics: []models.Topic{
+		topic("economy", "Economy",
+			topic("inflationandpriceindices", "Inflation and price indices"),
+			topic("grossdomesticproduct", "GDP"),
+		),
+		topic("people", "People",
+			topic("health", "Health"),
+		),
+	}}
+
+	tests := []struct {
+		name          string
+		newTaxonomy   models.Taxonomy
+		wantAdded     []topicChange
+		wantRemoved   []topicChange
+		wantRenamed   []topicChange
+		wantMoved     []topicChange
+		wantUnchanged int
+	}{
+		{
+			name:          "unchanged",
+			newTaxonomy:   old,
+			wantUnchanged: 5,
+		},
+		{
+			name: "added",
+			newTaxonomy: models.Taxonomy{Topics: append(old.Topics[:len(old.Topics):len(old.Topics)],
+				topic("business", "Business", topic("retail", "Retail")),
+			)},
+			wantAdded: []topicChange{
+				{Topic: "business", New: topicEntry{Title: "Business"}},
+				{Topic: "retail", New: topicEntry{Title: "Retail", Parent: "business"}},
+			},
+			wantUnchanged: 5,
+		},
+		{
+			name: "removed",
+			newTaxonomy: models.Taxonomy{Topics: []models.Topic{
+				old.Topics[0],
+			}},
+			wantRemoved: []topicChange{
+				{Topic: "health", Old: topicEntry{Title: "Health", Parent: "people"}},
+				{Topic: "people", Old: topicEntry{Title: "People"}},
+			},
+			wantUnchanged: 3,
+		},
+		{
+			name: "renamed",
+			newTaxonomy: models.Taxonomy{Topics: []models.Topic{
+				topic("economy", "Economy",
+					topic("inflationandpriceindices", "Inflation and price indices"),
+					topic("grossdomesticproduct", "Gross domestic product"),
+				),
+				old.Topics[1],
+			}},
+			wantRenamed: []topicChange{
+				{Topic: "grossdomesticproduct", Old: topicEntry{Title: "GDP", Parent: "economy"}, New: topicEntry{Title: "Gross domestic product", Parent: "economy"}},
+			},
+			wantUnchanged: 4,
+		},
+		{
+			name: "moved and renamed",
+			newTaxonomy: models.Taxonomy{Topics: []models.Topic{
+				topic("economy", "Economy",
+					topic("inflationandpriceindices", "Inflation and price indices",
+						topic("health", "Health prices"),
+					),
+					topic("grossdomesticproduct", "GDP"),
+				),
+				topic("people", "People"),
+			}},
+			wantRenamed: []topicChange{
+				{Topic: "health", Old: topicEntry{Title: "Health", Parent: "people"}, New: topicEntry{Title: "Health prices", Parent: "economy/inflationandpriceindices"}},
+			},
+			wantMoved: []topicChange{
+				{Topic: "health", Old: topicEntry{Title: "Health", Parent: "people"}, New: topicEntry{Title: "Health prices", Parent: "economy/inflationandpriceindices"}},
+			},
+			wantUnchanged: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffTaxonomies(old, tt.newTaxonomy)
+
+			want := taxonomyDiff{
+				Added:     tt.wantAdded,
+				Removed:   tt.wantRemoved,
+				Renamed:   tt.wantRenamed,
+				Moved:     tt.wantMoved,
+				Unchanged: tt.wantUnchanged,
+			}
+			if !reflect.DeepEqual(diff, want) {
+				t.Errorf("diff = %+v, want %+v", diff, want)
+			}
+		})
+	}
+}
+
+func TestPrintDiff(t *testing.T) {
+	diff := taxonomyDiff{
+		Added:     []topicChange{{Topic: "business", New: topicEntry{Title: "Business"}}},
+		Removed:   []topicChange{{Topic: "people", Old: topicEntry{Title: "People"}}},
+		Renamed:   []topicChange{{Topic: "gdp", Old: topicEntry{Title: "GDP"}, New: topicEntry{Title: "Gross domestic product"}}},
+		Moved:     []topicChange{{Topic: "health", Old: topicEntry{Parent: "people"}, New: topicEntry{}}},
+		Unchanged: 3,
+	}
+
+	var b bytes.Buffer
+	printDiff(&b, diff)
+
+	want := "+ business\tBusiness\n" +
+		"- people\tPeople\n" +
+		"~ gdp\tGDP -> Gross domestic product\n" +
+		"> health\tpeople -> (top level)\n" +
+		"added: 1, removed: 1, renamed: 1, moved: 1, unchanged: 3\n"
+	if b.String() != want {
+		t.Errorf("diff = %q, want %q", b.String(), want)
+	}
+
+	if diff.changes() != 4 {
+		t.Errorf("changes = %d, want 4", diff.changes())
+	}
+}
@@ -0,0 +1,246 @@
+package taxonomy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+const (
+	onsWebsite = "https://www.ons.gov.uk"
+
+	taxonomyLandingPage = "taxonomy_landing_page"
+)
+
+const (
+	defaultCacheTTL = time.Hour
+	defaultTimeout  = 30 * time.Second
+	defaultWorkers  = 4
+)
+
+var (
+	defaultFilename = "../data/taxonomy.json"
+	defaultCacheDir = filepath.Join(os.TempDir(), "dataset-taxonomy-cache")
+)
+
+// ErrChanged is returned when checking a taxonomy file that is out of date
+var ErrChanged = errors.New("taxonomy has changed")
+
+// Options holds the settings used to fetch the taxonomy, each of which can be set by a
+// command line flag
+type Options struct {
+	CacheDir string
+	CacheTTL time.Duration
+	Check    bool
+	Filename string
+	Timeout  time.Duration
+	Workers  int
+}
+
+// DefaultOptions returns the options of a fetch run from the scripts directory
+func DefaultOptions() Options {
+	return Options{
+		CacheDir: defaultCacheDir,
+		CacheTTL: defaultCacheTTL,
+		Filename: defaultFilename,
+		Timeout:  defaultTimeout,
+		Workers:  defaultWorkers,
+	}
+}
+
+// Register adds the taxonomy flags to the flag set, each flag defaults to the value
+// already set in the options
+func (o *Options) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Filename, "filename", o.Filename, "the name and path of the file location to create file")
+	fs.IntVar(&o.Workers, "workers", o.Workers, "the number of requests sent to the ons website at the same time")
+	fs.DurationVar(&o.Timeout, "timeout", o.Timeout, "the time allowed for each request to the ons website")
+	fs.StringVar(&o.CacheDir, "cache-dir", o.CacheDir, "the directory responses from the ons website are cached in")
+	fs.DurationVar(&o.CacheTTL, "cache-ttl", o.CacheTTL, "how long cached responses are used for, set to 0 to disable the cache")
+	fs.BoolVar(&o.Check, "check", o.Check, "compare the taxonomy with the file without writing it, exiting with a non-zero status if it has changed")
+}
+
+// Run crawls the taxonomy of the ons website and prints how it differs from the taxonomy
+// in the file, before writing it to the file. When checking, the file is not written and
+// ErrChanged is returned if the taxonomy differs
+func Run(ctx context.Context, opts Options) error {
+	if opts.Filename == "" {
+		opts.Filename = defaultFilename
+	}
+
+	if opts.Workers < 1 {
+		opts.Workers = defaultWorkers
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+
+	filename := opts.Filename
+
+	log.Event(ctx, "taxonomy options", log.INFO, log.Data{"ons_website": onsWebsite, "filename": filename, "workers": opts.Workers, "timeout": opts.Timeout.String(), "cache_dir": opts.CacheDir, "cache_ttl": opts.CacheTTL.String(), "check": opts.Check})
+
+	cache, err := newResponseCache(opts.CacheDir, opts.CacheTTL)
+	if err != nil {
+		log.Event(ctx, "failed to create cache directory", log.ERROR, log.Error(err), log.Data{"cache_dir": opts.CacheDir})
+		return err
+	}
+
+	// Call ons website for top level taxonomy
+	taxonomy, err := newCrawler(onsWebsite, opts.Workers, opts.Timeout, cache).crawl(ctx)
+	if err != nil {
+		log.Event(ctx, "failed to retrieve taxonomy data from ons website", log.ERROR, log.Error(err))
+		return err
+	}
+
+	// Store doc to file
+	file, err := json.MarshalIndent(taxonomy, "", "  ")
+	if err != nil {
+		log.Event(ctx, "failed to marshal taxonomy with indentation", log.ERROR, log.Error(err))
+		return err
+	}
+
+	existingFile, existingTaxonomy, err := readTaxonomyFile(filename)
+	if err != nil {
+		log.Event(ctx, "failed to read existing taxonomy file", log.ERROR, log.Error(err), log.Data{"filename": filename})
+		return err
+	}
+
+	diff := diffTaxonomies(existingTaxonomy, *taxonomy)
+	printDiff(os.Stdout, diff)
+
+	if opts.Check {
+		if diff.changes() > 0 {
+			log.Event(ctx, "taxonomy has changed", log.ERROR, log.Error(ErrChanged), log.Data{"filename": filename, "changes": diff.changes()})
+			return ErrChanged
+		}
+
+		log.Event(ctx, "taxonomy has not changed", log.INFO, log.Data{"filename": filename})
+		return nil
+	}
+
+	if bytes.Equal(existingFile, file) {
+		log.Event(ctx, "taxonomy has not changed, file not written", log.INFO, log.Data{"filename": filename})
+		return nil
+	}
+
+	if err = writeFile(filename, file); err != nil {
+		log.Event(ctx, "failed to write to file", log.ERROR, log.Error(err), log.Data{"filename": filename})
+		return err
+	}
+
+	return nil
+}
+
+// writeFile writes the data to a temporary file in the same directory and renames it
+// over the file, so the file is never left partly written
+func writeFile(filename string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	// temporary files are only readable by their owner
+	if err = os.Chmod(file.Name(), 0644); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err = os.Rename(file.Name(), filename); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
+
+// readTaxonomyFile reads the taxonomy previously stored in the file, a file that does
+// not exist holds an empty taxonomy
+func readTaxonomyFile(filename string) ([]byte, models.Taxonomy, error) {
+	var taxonomy models.Taxonomy
+
+	file, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, taxonomy, nil
+	}
+	if err != nil {
+		return nil, taxonomy, err
+	}
+
+	if err = json.Unmarshal(file, &taxonomy); err != nil {
+		return nil, taxonomy, err
+	}
+
+	return file, taxonomy, nil
+}
+
+type TopTaxonomy struct {
+	Sections    []Section   `json:"sections"`
+	URI         string      `json:"uri"`
+	Description Description `json:"description"`
+}
+
+type Section struct {
+	Theme Theme `json:"theme"`
+}
+
+type Theme struct {
+	URI string `json:"uri"`
+}
+
+func CreateFirstLevelTaxonomy(ctx context.Context, reader io.Reader) (*TopTaxonomy, error) {
+
+	b, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, errors.New("unable to read bytes")
+	}
+
+	var topTaxonomy TopTaxonomy
+
+	if err = json.Unmarshal(b, &topTaxonomy); err != nil {
+		return nil, errors.New("failed to parse json body")
+	}
+
+	return &topTaxonomy, nil
+}
+
+type ChildTaxonomy struct {
+	Sections    []ChildSection `json:"sections"`
+	Type        string         `json:"type"`
+	URI         string         `json:"uri"`
+	Description Description    `json:"description"`
+}
+
+type Description struct {
+	Title string `json:"title"`
+}
+
+type ChildSection struct {
+	URI string `json:"uri"`
+}
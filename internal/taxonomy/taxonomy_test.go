@@ -0,0 +1,42 @@
+package taxonomy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	filename := filepath.Join(dir, "taxonomy.json")
+	if err := ioutil.WriteFile(filename, []byte(`{"topics":[]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFile(filename, []byte(`{"topics":[{"title":"Economy"}]}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil || string(b) != `{"topics":[{"title":"Economy"}]}` {
+		t.Errorf("file = %s, %v, want the new taxonomy", b, err)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("file mode = %v, %v, want 0644", info.Mode().Perm(), err)
+	}
+
+	// the temporary file is renamed over the file, leaving nothing else behind
+	files, err := ioutil.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Errorf("directory holds %d files, %v, want only the taxonomy file", len(files), err)
+	}
+
+	if err = writeFile(filepath.Join(dir, "missing", "taxonomy.json"), nil); err == nil {
+		t.Error("expected an error writing to a missing directory")
+	}
+}
@@ -0,0 +1,167 @@
+package upload
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	es "github.com/ONSdigital/dp-census-dataset-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// rowDocument is an action on a dataset document along with the csv row it was read
+// from, documents removed from the csv have no row
+type rowDocument struct {
+	Row     int
+	Action  string
+	ID      string
+	Dataset *Dataset
+}
+
+// failedDocument is a dataset document that elasticsearch failed to index
+type failedDocument struct {
+	Row    int    `json:"row,omitempty"`
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status int    `json:"status,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// uploadSummary holds the number of documents indexed and deleted and the documents that failed
+type uploadSummary struct {
+	Indexed  int              `json:"indexed"`
+	Deleted  int              `json:"deleted"`
+	Failed   int              `json:"failed"`
+	Failures []failedDocument `json:"failures,omitempty"`
+}
+
+func (summary *uploadSummary) add(result uploadSummary) {
+	summary.Indexed += result.Indexed
+	summary.Deleted += result.Deleted
+	summary.Failed += result.Failed
+	summary.Failures = append(summary.Failures, result.Failures...)
+}
+
+// indexDocuments sends the documents to elasticsearch in batches of bulk requests,
+// spread across a number of workers, reporting every document that failed to index
+func indexDocuments(ctx context.Context, esAPI *es.API, indexName string, docs []rowDocument, batchSize, workers int) uploadSummary {
+	batches := make(chan []rowDocument)
+	results := make(chan uploadSummary)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				results <- indexBatch(ctx, esAPI, indexName, batch)
+			}
+		}()
+	}
+
+	go func() {
+		for start := 0; start < len(docs); start += batchSize {
+			end := start + batchSize
+			if end > len(docs) {
+				end = len(docs)
+			}
+
+			batches <- docs[start:end]
+		}
+		close(batches)
+
+		wg.Wait()
+		close(results)
+	}()
+
+	var summary uploadSummary
+	for result := range results {
+		summary.add(result)
+	}
+
+	// batches finish in any order, so report failures in the order of the csv
+	sort.Slice(summary.Failures, func(i, j int) bool {
+		if summary.Failures[i].Row != summary.Failures[j].Row {
+			return summary.Failures[i].Row < summary.Failures[j].Row
+		}
+		return summary.Failures[i].ID < summary.Failures[j].ID
+	})
+
+	return summary
+}
+
+// indexBatch sends a single bulk request, when the request itself fails every document
+// in the batch is reported as failed with the error of the request
+func indexBatch(ctx context.Context, esAPI *es.API, indexName string, batch []rowDocument) uploadSummary {
+	actions := make([]es.BulkAction, len(batch))
+	for i, doc := range batch {
+		actions[i] = es.BulkAction{
+			Action:   doc.Action,
+			ID:       doc.ID,
+			Document: doc.Dataset,
+		}
+	}
+
+	logData := log.Data{"first_id": batch[0].ID, "documents": len(batch)}
+
+	response, status, err := esAPI.BulkRequest(ctx, indexName, actions)
+	if err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to send bulk request", log.ERROR, log.Error(err), logData)
+
+		summary := uploadSummary{Failed: len(batch)}
+		for _, doc := range batch {
+			summary.Failures = append(summary.Failures, failedDocument{
+				Row:    doc.Row,
+				ID:     doc.ID,
+				Title:  doc.Dataset.Title,
+				Status: status,
+				Reason: err.Error(),
+			})
+		}
+
+		return summary
+	}
+
+	var summary uploadSummary
+	for i, doc := range batch {
+		if i >= len(response.Items) {
+			summary.Failed++
+			summary.Failures = append(summary.Failures, failedDocument{
+				Row:    doc.Row,
+				ID:     doc.ID,
+				Title:  doc.Dataset.Title,
+				Reason: "missing from bulk response",
+			})
+			continue
+		}
+
+		// each item is keyed on its action, a successful delete is not an indexed document
+		for action, item := range response.Items[i] {
+			if item.Error == nil {
+				if action == es.BulkDelete {
+					summary.Deleted++
+				} else {
+					summary.Indexed++
+				}
+				continue
+			}
+
+			summary.Failed++
+			summary.Failures = append(summary.Failures, failedDocument{
+				Row:    doc.Row,
+				ID:     doc.ID,
+				Title:  doc.Dataset.Title,
+				Status: item.Status,
+				Reason: item.Error.Type + ": " + item.Error.Reason,
+			})
+		}
+	}
+
+	logData["indexed"] = summary.Indexed
+	logData["deleted"] = summary.Deleted
+	logData["failed"] = summary.Failed
+	log.Event(ctx, "sent bulk request", log.INFO, logData)
+
+	return summary
+}
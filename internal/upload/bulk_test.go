@@ -0,0 +1,99 @@
+package upload
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	es "github.com/ONSdigital/dp-census-dataset-search-api/internal/elasticsearch"
+)
+
+func TestIndexDocuments(t *testing.T) {
+	doc := func(row int, action, id string) rowDocument {
+		return rowDocument{Row: row, Action: action, ID: id, Dataset: &Dataset{Alias: id, Title: id}}
+	}
+
+	docs := []rowDocument{
+		doc(2, es.BulkIndex, "a"),
+		doc(3, es.BulkIndex, "b"),
+		doc(4, es.BulkIndex, "c"),
+		doc(5, es.BulkIndex, "d"),
+		doc(0, es.BulkDelete, "e"),
+		doc(0, es.BulkDelete, "f"),
+		doc(0, es.BulkDelete, "g"),
+	}
+
+	tests := []struct {
+		name         string
+		failIDs      map[string]bool
+		wantIndexed  int
+		wantDeleted  int
+		wantFailures []failedDocument
+	}{
+		{
+			name:        "deletes are counted apart from indexed documents",
+			wantIndexed: 4,
+			wantDeleted: 3,
+		},
+		{
+			name:        "failed documents are reported in row order",
+			failIDs:     map[string]bool{"d": true, "b": true, "f": true},
+			wantIndexed: 2,
+			wantDeleted: 2,
+			wantFailures: []failedDocument{
+				{ID: "f", Title: "f", Status: http.StatusBadRequest, Reason: "mapper_parsing_exception: failed to parse"},
+				{Row: 3, ID: "b", Title: "b", Status: http.StatusBadRequest, Reason: "mapper_parsing_exception: failed to parse"},
+				{Row: 5, ID: "d", Title: "d", Status: http.StatusBadRequest, Reason: "mapper_parsing_exception: failed to parse"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeElasticsearch{failIDs: tt.failIDs}
+			esAPI, closeServer := newFakeAPI(t, fake)
+			defer closeServer()
+
+			summary := indexDocuments(context.Background(), esAPI, "datasets", docs, 2, 3)
+
+			if summary.Indexed != tt.wantIndexed || summary.Deleted != tt.wantDeleted || summary.Failed != len(tt.wantFailures) {
+				t.Errorf("indexed, deleted, failed = %d, %d, %d, want %d, %d, %d",
+					summary.Indexed, summary.Deleted, summary.Failed, tt.wantIndexed, tt.wantDeleted, len(tt.wantFailures))
+			}
+			if !reflect.DeepEqual(summary.Failures, tt.wantFailures) {
+				t.Errorf("failures = %+v, want %+v", summary.Failures, tt.wantFailures)
+			}
+
+			// every document is sent once, whichever worker sends its batch
+			sort.Strings(fake.bulkActions)
+			want := []string{"delete e", "delete f", "delete g", "index a", "index b", "index c", "index d"}
+			if !reflect.DeepEqual(fake.bulkActions, want) {
+				t.Errorf("bulk actions = %v, want %v", fake.bulkActions, want)
+			}
+		})
+	}
+}
+
+func TestIndexBatchRequestFails(t *testing.T) {
+	fake := &fakeElasticsearch{bulkStatus: http.StatusBadRequest}
+	esAPI, closeServer := newFakeAPI(t, fake)
+	defer closeServer()
+
+	batch := []rowDocument{
+		{Row: 2, Action: es.BulkIndex, ID: "a", Dataset: &Dataset{Title: "A"}},
+		{Action: es.BulkDelete, ID: "b", Dataset: &Dataset{Title: "B"}},
+	}
+
+	summary := indexBatch(context.Background(), esAPI, "datasets", batch)
+
+	if summary.Indexed != 0 || summary.Deleted != 0 || summary.Failed != 2 || len(summary.Failures) != 2 {
+		t.Fatalf("summary = %+v, want every document failed", summary)
+	}
+	for _, failure := range summary.Failures {
+		if failure.Status != http.StatusBadRequest {
+			t.Errorf("failure %s status = %d, want %d", failure.ID, failure.Status, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	es "github.com/ONSdigital/dp-census-dataset-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+// fakeElasticsearch stands in for the parts of elasticsearch used by an upload, holding
+// the indices and aliases in memory and recording the changes made to them
+type fakeElasticsearch struct {
+	mutex   sync.Mutex
+	indices map[string]bool
+	aliases map[string][]string
+
+	// failIDs are the documents whose bulk action fails, bulkStatus fails a whole bulk request
+	failIDs    map[string]bool
+	bulkStatus int
+
+	aliasRequests []string
+	deleted       []string
+	bulkActions   []string
+}
+
+// newFakeAPI returns an API for the fake along with a func closing its server
+func newFakeAPI(t *testing.T, fake *fakeElasticsearch) (*es.API, func()) {
+	srv := httptest.NewServer(fake)
+
+	cli, err := es.NewClient(es.TLSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := es.NewElasticSearchAPI(cli, []string{srv.URL}, es.Auth{}, es.Policy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return api, func() {
+		api.Close()
+		srv.Close()
+	}
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	body, _ := ioutil.ReadAll(req.Body)
+	path := strings.TrimPrefix(req.URL.Path, "/")
+
+	switch {
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "_alias/"):
+		indices := f.aliases[strings.TrimPrefix(path, "_alias/")]
+		if len(indices) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		response := make(map[string]interface{})
+		for _, index := range indices {
+			response[index] = map[string]interface{}{}
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "_cat/indices/"):
+		prefix := strings.TrimSuffix(strings.TrimPrefix(path, "_cat/indices/"), "*")
+		rows := []map[string]string{}
+		for index := range f.indices {
+			if strings.HasPrefix(index, prefix) {
+				rows = append(rows, map[string]string{"index": index})
+			}
+		}
+		json.NewEncoder(w).Encode(rows)
+
+	case req.Method == http.MethodPost && path == "_aliases":
+		f.aliasRequests = append(f.aliasRequests, string(body))
+		f.applyAliasActions(body)
+		w.Write([]byte(`{"acknowledged":true}`))
+
+	case req.Method == http.MethodPost && path == "_bulk":
+		f.bulk(w, body)
+
+	case req.Method == http.MethodHead:
+		if !f.indices[path] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+	case req.Method == http.MethodDelete:
+		delete(f.indices, path)
+		f.deleted = append(f.deleted, path)
+		w.Write([]byte(`{"acknowledged":true}`))
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (f *fakeElasticsearch) applyAliasActions(body []byte) {
+	var request struct {
+		Actions []map[string]struct {
+			Index string `json:"index"`
+			Alias string `json:"alias"`
+		} `json:"actions"`
+	}
+	json.Unmarshal(body, &request)
+
+	for _, action := range request.Actions {
+		for name, target := range action {
+			switch name {
+			case "add":
+				f.aliases[target.Alias] = append(f.aliases[target.Alias], target.Index)
+			case "remove":
+				var kept []string
+				for _, index := range f.aliases[target.Alias] {
+					if index != target.Index {
+						kept = append(kept, index)
+					}
+				}
+				f.aliases[target.Alias] = kept
+			case "remove_index":
+				delete(f.indices, target.Index)
+			}
+		}
+	}
+}
+
+// bulk responds to each action in the bulk request, failing the documents in failIDs
+func (f *fakeElasticsearch) bulk(w http.ResponseWriter, body []byte) {
+	if f.bulkStatus != 0 {
+		w.WriteHeader(f.bulkStatus)
+		return
+	}
+
+	response := models.BulkResponse{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var metadata map[string]struct {
+			ID string `json:"_id"`
+		}
+		json.Unmarshal(scanner.Bytes(), &metadata)
+
+		for action, target := range metadata {
+			f.bulkActions = append(f.bulkActions, action+" "+target.ID)
+
+			item := models.BulkItem{ID: target.ID, Status: http.StatusOK}
+			if f.failIDs[target.ID] {
+				item.Status = http.StatusBadRequest
+				item.Error = &models.BulkItemError{Type: "mapper_parsing_exception", Reason: "failed to parse"}
+				response.Errors = true
+			}
+			response.Items = append(response.Items, map[string]models.BulkItem{action: item})
+
+			// the document follows the metadata of an index action
+			if action == es.BulkIndex {
+				scanner.Scan()
+			}
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeElasticsearch) indexNames() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var names []string
+	for index := range f.indices {
+		names = append(names, index)
+	}
+	sort.Strings(names)
+
+	return names
+}
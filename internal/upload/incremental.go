@@ -0,0 +1,234 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	es "github.com/ONSdigital/dp-census-dataset-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// datasetDiff holds the datasets added to, changed in or removed from the csv
+// compared with the documents in the index
+type datasetDiff struct {
+	Added     []rowDocument
+	Updated   []rowDocument
+	Removed   []rowDocument
+	Unchanged int
+	Skipped   int
+}
+
+// changes returns the actions needed to bring the index in line with the csv
+func (diff datasetDiff) changes() []rowDocument {
+	changes := make([]rowDocument, 0, len(diff.Added)+len(diff.Updated)+len(diff.Removed))
+	changes = append(changes, diff.Added...)
+	changes = append(changes, diff.Updated...)
+	changes = append(changes, diff.Removed...)
+
+	return changes
+}
+
+// upsertDocs updates the index behind the alias in place, indexing datasets that are new
+// or have changed and deleting datasets whose alias no longer appears in the csv. Datasets
+// with a skipped alias are left as they are in the index
+func (u *uploader) upsertDocs(ctx context.Context, esAPI *es.API, alias string, docs []rowDocument, skipped map[string]bool) error {
+	indexName, err := resolveIndex(ctx, esAPI, alias)
+	if err != nil {
+		log.Event(ctx, "failed to find index to update", log.ERROR, log.Error(err), log.Data{"alias": alias})
+		return err
+	}
+
+	logData := log.Data{"alias": alias, "index": indexName}
+
+	existing, status, err := esAPI.GetAllDocuments(ctx, indexName)
+	if err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to retrieve existing dataset docs", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	diff, err := diffDocuments(docs, existing, skipped)
+	if err != nil {
+		log.Event(ctx, "failed to compare dataset docs with index", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	printDiff(os.Stdout, diff)
+
+	summary := indexDocuments(ctx, esAPI, indexName, diff.changes(), u.opts.BatchSize, u.opts.Workers)
+
+	logData["indexed"] = summary.Indexed
+	logData["deleted"] = summary.Deleted
+	logData["failed"] = summary.Failed
+
+	if status, err := esAPI.RefreshIndex(ctx, indexName); err != nil {
+		log.Event(ctx, "failed to refresh index", log.WARN, log.Error(err), log.Data{"index": indexName, "status": status})
+	}
+
+	if summary.Failed > 0 {
+		logData["failures"] = summary.Failures
+		err = fmt.Errorf("%d documents failed to update", summary.Failed)
+		log.Event(ctx, "failed to update dataset docs", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	log.Event(ctx, "updated dataset docs in index", log.INFO, logData)
+
+	return nil
+}
+
+// resolveIndex returns the index the alias points to, or when there is no alias an index
+// with the same name, creating it if it does not exist
+func resolveIndex(ctx context.Context, esAPI *es.API, alias string) (string, error) {
+	indices, _, err := esAPI.GetAliasIndices(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(indices) {
+	case 1:
+		return indices[0], nil
+	case 0:
+	default:
+		return "", errors.New("alias points to more than one index")
+	}
+
+	exists, _, err := esAPI.IndexExists(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+
+	if !exists {
+		if _, err = esAPI.CreateSearchIndex(ctx, alias, mappingsFile); err != nil {
+			return "", err
+		}
+	}
+
+	return alias, nil
+}
+
+// skippedAliases returns the aliases of records that were not turned into documents, along
+// with the number of skipped records that have no alias
+func skippedAliases(records []inputRecord, docs []rowDocument) (map[string]bool, int) {
+	uploaded := make(map[string]bool)
+	for _, doc := range docs {
+		uploaded[doc.ID] = true
+	}
+
+	skipped := make(map[string]bool)
+	var unidentified int
+
+	for _, record := range records {
+		alias := record.Input.Alias
+		switch {
+		case alias == "":
+			unidentified++
+		case !uploaded[alias]:
+			skipped[alias] = true
+		}
+	}
+
+	return skipped, unidentified
+}
+
+// diffDocuments compares the csv documents with the source of the documents in the index,
+// a document has changed when its json differs from the source. Documents in the index
+// with a skipped alias are never removed, as the record for them was invalid rather than
+// missing from the csv
+func diffDocuments(docs []rowDocument, existing map[string]json.RawMessage, skipped map[string]bool) (datasetDiff, error) {
+	var diff datasetDiff
+
+	seen := make(map[string]bool)
+	for _, doc := range docs {
+		seen[doc.ID] = true
+
+		source, ok := existing[doc.ID]
+		if !ok {
+			diff.Added = append(diff.Added, doc)
+			continue
+		}
+
+		changed, err := hasChanged(doc.Dataset, source)
+		if err != nil {
+			return diff, err
+		}
+
+		if changed {
+			diff.Updated = append(diff.Updated, doc)
+		} else {
+			diff.Unchanged++
+		}
+	}
+
+	for id, source := range existing {
+		if seen[id] {
+			continue
+		}
+
+		if skipped[id] {
+			diff.Skipped++
+			continue
+		}
+
+		dataset := &Dataset{}
+		if err := json.Unmarshal(source, dataset); err != nil {
+			return diff, err
+		}
+
+		diff.Removed = append(diff.Removed, rowDocument{
+			Action:  es.BulkDelete,
+			ID:      id,
+			Dataset: dataset,
+		})
+	}
+
+	sort.Slice(diff.Removed, func(i, j int) bool {
+		return diff.Removed[i].ID < diff.Removed[j].ID
+	})
+
+	return diff, nil
+}
+
+// hasChanged compares the dataset with the source stored in the index, the source is
+// decoded and encoded again so fields are in the same order as the dataset
+func hasChanged(dataset *Dataset, source json.RawMessage) (bool, error) {
+	stored := &Dataset{}
+	if err := json.Unmarshal(source, stored); err != nil {
+		return false, err
+	}
+
+	storedBytes, err := json.Marshal(stored)
+	if err != nil {
+		return false, err
+	}
+
+	datasetBytes, err := json.Marshal(dataset)
+	if err != nil {
+		return false, err
+	}
+
+	return !bytes.Equal(storedBytes, datasetBytes), nil
+}
+
+// printDiff writes the added (+), updated (~) and removed (-) datasets followed by a count of each
+func printDiff(w io.Writer, diff datasetDiff) {
+	for _, doc := range diff.Added {
+		fmt.Fprintf(w, "+ %s\t%s\n", doc.ID, doc.Dataset.Title)
+	}
+
+	for _, doc := range diff.Updated {
+		fmt.Fprintf(w, "~ %s\t%s\n", doc.ID, doc.Dataset.Title)
+	}
+
+	for _, doc := range diff.Removed {
+		fmt.Fprintf(w, "- %s\t%s\n", doc.ID, doc.Dataset.Title)
+	}
+
+	fmt.Fprintf(w, "added: %d, updated: %d, removed: %d, unchanged: %d, skipped: %d\n", len(diff.Added), len(diff.Updated), len(diff.Removed), diff.Unchanged, diff.Skipped)
+}
@@ -0,0 +1,130 @@
+package upload
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	es "github.com/ONSdigital/dp-census-dataset-search-api/internal/elasticsearch"
+)
+
+func TestDiffDocuments(t *testing.T) {
+	stored := func(alias, title string) json.RawMessage {
+		b, err := json.Marshal(&Dataset{Alias: alias, Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	doc := func(alias, title string) rowDocument {
+		return rowDocument{Action: es.BulkIndex, ID: alias, Dataset: &Dataset{Alias: alias, Title: title}}
+	}
+
+	tests := []struct {
+		name      string
+		docs      []rowDocument
+		existing  map[string]json.RawMessage
+		skipped   map[string]bool
+		added     []string
+		updated   []string
+		removed   []string
+		unchanged int
+		kept      int
+	}{
+		{
+			name:      "new datasets are added",
+			docs:      []rowDocument{doc("a", "A"), doc("b", "B")},
+			existing:  map[string]json.RawMessage{"a": stored("a", "A")},
+			added:     []string{"b"},
+			unchanged: 1,
+		},
+		{
+			name:     "changed datasets are updated",
+			docs:     []rowDocument{doc("a", "A changed")},
+			existing: map[string]json.RawMessage{"a": stored("a", "A")},
+			updated:  []string{"a"},
+		},
+		{
+			name:      "datasets missing from the file are removed in order",
+			docs:      []rowDocument{doc("a", "A")},
+			existing:  map[string]json.RawMessage{"a": stored("a", "A"), "c": stored("c", "C"), "b": stored("b", "B")},
+			removed:   []string{"b", "c"},
+			unchanged: 1,
+		},
+		{
+			name:      "datasets with a skipped row are kept",
+			docs:      []rowDocument{doc("a", "A")},
+			existing:  map[string]json.RawMessage{"a": stored("a", "A"), "b": stored("b", "B"), "c": stored("c", "C")},
+			skipped:   map[string]bool{"b": true},
+			removed:   []string{"c"},
+			unchanged: 1,
+			kept:      1,
+		},
+		{
+			name:     "a skipped row for a dataset not in the index changes nothing",
+			docs:     []rowDocument{doc("a", "A")},
+			existing: map[string]json.RawMessage{},
+			skipped:  map[string]bool{"b": true},
+			added:    []string{"a"},
+		},
+	}
+
+	ids := func(docs []rowDocument) []string {
+		var ids []string
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+		return ids
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := diffDocuments(tt.docs, tt.existing, tt.skipped)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := ids(diff.Added); !reflect.DeepEqual(got, tt.added) {
+				t.Errorf("added = %v, want %v", got, tt.added)
+			}
+			if got := ids(diff.Updated); !reflect.DeepEqual(got, tt.updated) {
+				t.Errorf("updated = %v, want %v", got, tt.updated)
+			}
+			if got := ids(diff.Removed); !reflect.DeepEqual(got, tt.removed) {
+				t.Errorf("removed = %v, want %v", got, tt.removed)
+			}
+			for _, doc := range diff.Removed {
+				if doc.Action != es.BulkDelete {
+					t.Errorf("removed %s has action %s, want %s", doc.ID, doc.Action, es.BulkDelete)
+				}
+			}
+			if diff.Unchanged != tt.unchanged {
+				t.Errorf("unchanged = %d, want %d", diff.Unchanged, tt.unchanged)
+			}
+			if diff.Skipped != tt.kept {
+				t.Errorf("skipped = %d, want %d", diff.Skipped, tt.kept)
+			}
+		})
+	}
+}
+
+func TestSkippedAliases(t *testing.T) {
+	records := []inputRecord{
+		{Row: 2, Input: datasetInput{Dataset: Dataset{Alias: "a"}}},
+		{Row: 3, Input: datasetInput{Dataset: Dataset{Alias: "b"}}, Issues: []validationIssue{newIssue(3, "b", "dimensions", severityError, "mismatch")}},
+		{Row: 4, Issues: []validationIssue{newIssue(4, "", "", severityError, "row has 2 columns, expected 7")}},
+		// a duplicate of an uploaded alias is not a skipped dataset
+		{Row: 5, Input: datasetInput{Dataset: Dataset{Alias: "a"}}},
+	}
+	docs := []rowDocument{{ID: "a"}}
+
+	skipped, unidentified := skippedAliases(records, docs)
+
+	if want := map[string]bool{"b": true}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+	if unidentified != 1 {
+		t.Errorf("unidentified = %d, want 1", unidentified)
+	}
+}
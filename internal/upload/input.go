@@ -0,0 +1,249 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/datasetcsv"
+)
+
+// A list of the input file formats datasets can be read from
+const (
+	formatCSV    = "csv"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// datasetInput represents a dataset read from an input file, it mirrors the Dataset
+// document. The topic can be given on its own or as the last of the topics, either
+// way the full path of topics is looked up in the taxonomy
+type datasetInput struct {
+	Dataset
+	Topic string `json:"topic,omitempty"`
+}
+
+// inputRecord is a dataset read from a row of a csv, a line of ndjson or an element of
+// a json array, Row is the line the csv row or ndjson line starts on, or the position
+// in the json array, a record that could not be read holds the reasons in Issues
+type inputRecord struct {
+	Row    int
+	Input  datasetInput
+	Issues []validationIssue
+}
+
+// detectFormat returns the format set by flag, or otherwise the format matching the
+// extension of the file, defaulting to csv
+func detectFormat(format, filename string) (string, error) {
+	switch format {
+	case formatCSV, formatJSON, formatNDJSON:
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("invalid input format %q, expected one of csv, json or ndjson", format)
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return formatJSON, nil
+	case ".ndjson", ".jsonl":
+		return formatNDJSON, nil
+	default:
+		return formatCSV, nil
+	}
+}
+
+// readInput reads every dataset record from the input in the given format
+func readInput(r io.Reader, format string) ([]inputRecord, error) {
+	switch format {
+	case formatJSON:
+		return readJSON(r)
+	case formatNDJSON:
+		return readNDJSON(r)
+	default:
+		return readCSV(r)
+	}
+}
+
+// readCSV reads a record from each row of a csv with a header row, dimension names and
+// labels are colon separated so neither can contain a colon
+func readCSV(r io.Reader) ([]inputRecord, error) {
+	lines := &lineReader{r: bufio.NewReader(r)}
+	reader := csv.NewReader(lines)
+	// rows with the wrong number of columns are reported against the row rather than
+	// failing the whole file
+	reader.FieldsPerRecord = -1
+
+	headerRow, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	headerIndex, err := check(headerRow)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []inputRecord
+
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// the row is the line the record starts on, which is not the count of records read
+		// once a quoted column has spanned more than one line
+		row := lines.count - newlines(values)
+
+		if len(values) != len(headerRow) {
+			records = append(records, inputRecord{
+				Row: row,
+				Issues: []validationIssue{
+					newIssue(row, "", "", severityError, fmt.Sprintf("row has %d columns, expected %d", len(values), len(headerRow))),
+				},
+			})
+			continue
+		}
+
+		record := inputRecord{
+			Row: row,
+			Input: datasetInput{
+				Dataset: Dataset{
+					Alias:       values[headerIndex[datasetcsv.HeaderAlias]],
+					Description: values[headerIndex[datasetcsv.HeaderDescription]],
+					Link:        values[headerIndex[datasetcsv.HeaderLink]],
+					Title:       values[headerIndex[datasetcsv.HeaderTitle]],
+				},
+				Topic: values[headerIndex[datasetcsv.HeaderTopic]],
+			},
+		}
+
+		dimensionNames := values[headerIndex[datasetcsv.HeaderDimensionNames]]
+		dimensionLabels := values[headerIndex[datasetcsv.HeaderDimensionLabels]]
+
+		if dimensionNames == "" && dimensionLabels == "" {
+			records = append(records, record)
+			continue
+		}
+
+		dn := strings.Split(dimensionNames, datasetcsv.DimensionSeparator)
+		dl := strings.Split(dimensionLabels, datasetcsv.DimensionSeparator)
+
+		if len(dn) != len(dl) {
+			record.Issues = append(record.Issues, newIssue(row, record.Input.Alias, "dimensions", severityError,
+				fmt.Sprintf("dimension names and labels do not match up, %d names and %d labels", len(dn), len(dl))))
+			records = append(records, record)
+			continue
+		}
+
+		for i := range dn {
+			record.Input.Dimensions = append(record.Input.Dimensions, Dimension{
+				Label: dl[i],
+				Name:  dn[i],
+			})
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// lineReader counts the lines read from r, handing the csv reader no more than one line
+// on each read so the count is the last line of the record the csv reader has returned
+type lineReader struct {
+	r     *bufio.Reader
+	line  []byte
+	count int
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	if len(l.line) == 0 {
+		line, err := l.r.ReadSlice('\n')
+		if len(line) == 0 {
+			return 0, err
+		}
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return 0, err
+		}
+
+		// a long line is read in parts, the last line of a file may have no newline
+		if line[len(line)-1] == '\n' || err == io.EOF {
+			l.count++
+		}
+		l.line = line
+	}
+
+	n := copy(p, l.line)
+	l.line = l.line[n:]
+
+	return n, nil
+}
+
+// newlines returns the number of line breaks within the quoted columns of a record
+func newlines(values []string) int {
+	n := 0
+	for _, value := range values {
+		n += strings.Count(value, "\n")
+	}
+
+	return n
+}
+
+// readJSON reads a record from each element of a json array, the row is the position in the array
+func readJSON(r io.Reader) ([]inputRecord, error) {
+	var elements []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&elements); err != nil {
+		return nil, err
+	}
+
+	records := make([]inputRecord, 0, len(elements))
+	for i, element := range elements {
+		records = append(records, decodeRecord(i+1, element))
+	}
+
+	return records, nil
+}
+
+// readNDJSON reads a record from each line of newline delimited json, blank lines are skipped
+func readNDJSON(r io.Reader) ([]inputRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	var records []inputRecord
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		records = append(records, decodeRecord(line, scanner.Bytes()))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func decodeRecord(row int, data []byte) inputRecord {
+	record := inputRecord{Row: row}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&record.Input); err != nil {
+		record.Issues = append(record.Issues, newIssue(row, record.Input.Alias, "", severityError, err.Error()))
+	}
+
+	return record
+}
@@ -0,0 +1,175 @@
+package upload
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/datasetcsv"
+)
+
+func TestReadCSVRoundTrip(t *testing.T) {
+	rows := []datasetcsv.Row{
+		{
+			Alias:           "plain",
+			Description:     "A plain dataset",
+			DimensionLabels: []string{"Geography", "Time"},
+			DimensionNames:  []string{"geography", "time"},
+			Link:            "https://www.ons.gov.uk/datasets/plain",
+			Title:           "Plain",
+			Topic:           "economy",
+		},
+		{
+			Alias:           "quoted",
+			Description:     "Has \"quotes\", commas,\nnewlines and colons: all of them",
+			DimensionLabels: []string{"Sex, \"male\" or \"female\"", "Age\nin years"},
+			DimensionNames:  []string{"sex", "age"},
+			Link:            "https://www.ons.gov.uk/datasets/quoted",
+			Title:           "Title: with a colon, and a comma",
+			Topic:           "people",
+		},
+		{
+			Alias: "no-dimensions",
+			Title: "No dimensions",
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "datasets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "datasets.csv")
+
+	writer, err := datasetcsv.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err = writer.Write(row); err != nil {
+			t.Fatalf("unexpected error writing %s: %v", row.Alias, err)
+		}
+	}
+
+	err = writer.Write(datasetcsv.Row{Alias: "colon", DimensionNames: []string{"a:b"}, DimensionLabels: []string{"A"}})
+	if !errors.Is(err, datasetcsv.ErrDimensionSeparator) {
+		t.Fatalf("err = %v, want %v", err, datasetcsv.ErrDimensionSeparator)
+	}
+
+	if err = writer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	records, err := readCSV(file)
+	if err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+
+	if len(records) != len(rows) {
+		t.Fatalf("read %d records, want %d", len(records), len(rows))
+	}
+
+	for i, row := range rows {
+		record := records[i]
+		if len(record.Issues) > 0 {
+			t.Errorf("%s: unexpected issues %+v", row.Alias, record.Issues)
+		}
+
+		want := datasetInput{
+			Dataset: Dataset{
+				Alias:       row.Alias,
+				Description: row.Description,
+				Link:        row.Link,
+				Title:       row.Title,
+			},
+			Topic: row.Topic,
+		}
+		for j := range row.DimensionNames {
+			want.Dimensions = append(want.Dimensions, Dimension{Label: row.DimensionLabels[j], Name: row.DimensionNames[j]})
+		}
+
+		if !reflect.DeepEqual(record.Input, want) {
+			t.Errorf("%s: read %+v, want %+v", row.Alias, record.Input, want)
+		}
+	}
+}
+
+func TestReadCSVRows(t *testing.T) {
+	header := "title,alias,description,topic,ons-link,dimension-names,dimension-labels\n"
+	long := strings.Repeat("x", 10000)
+
+	tests := []struct {
+		name      string
+		input     string
+		wantRows  []int
+		wantIssue int
+	}{
+		{
+			name: "quoted columns spanning lines and blank lines",
+			input: header +
+				"A,a,\"spans\nthree\nlines\",,,,\n" +
+				"\n" +
+				"B,b,,,,,\n" +
+				"C,c,too,many,columns,,,\n",
+			wantRows:  []int{2, 6, 7},
+			wantIssue: 7,
+		},
+		{
+			name:     "lines longer than the read buffer",
+			input:    header + "A,a," + long + ",,,,\n" + "B,b,\"" + long + "\n" + long + "\",,,,\n" + "C,c,,,,,\n",
+			wantRows: []int{2, 3, 5},
+		},
+		{
+			name:     "crlf line endings",
+			input:    strings.Replace(header, "\n", "\r\n", 1) + "A,a,\"two\r\nlines\",,,,\r\n" + "B,b,,,,,\r\n",
+			wantRows: []int{2, 4},
+		},
+		{
+			name:      "no newline at the end of the file",
+			input:     header + "A,a,,,,,\n" + "B,b,,,",
+			wantRows:  []int{2, 3},
+			wantIssue: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := readCSV(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var rows []int
+			var issues []int
+			for _, record := range records {
+				rows = append(rows, record.Row)
+				for _, issue := range record.Issues {
+					issues = append(issues, issue.Row)
+				}
+			}
+
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("rows = %v, want %v", rows, tt.wantRows)
+			}
+
+			var wantIssues []int
+			if tt.wantIssue != 0 {
+				wantIssues = []int{tt.wantIssue}
+			}
+			if !reflect.DeepEqual(issues, wantIssues) {
+				t.Errorf("issue rows = %v, want %v", issues, wantIssues)
+			}
+		})
+	}
+}
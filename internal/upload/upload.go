@@ -0,0 +1,577 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/config"
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/datasetcsv"
+	es "github.com/ONSdigital/dp-census-dataset-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-dataset-search-api/internal/metadata"
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+	"github.com/ONSdigital/log.go/log"
+)
+
+const (
+	defaultFilename      = "cmd-datasets.csv"
+	defaultDimensionFile = "../data/dimensions.json"
+	defaultTaxonomyFile  = "../data/taxonomy.json"
+	defaultBatchSize     = 500
+	defaultRetainIndices = 2
+	defaultWorkers       = 4
+	indexTimestampFormat = "20060102150405"
+	mappingsFile         = "dataset-mappings.json"
+)
+
+// Dataset represents the data stored against a resource in elasticsearch index
+type Dataset struct {
+	Alias       string      `json:"alias"`
+	Description string      `json:"description"`
+	Dimensions  []Dimension `json:"dimensions"`
+	Link        string      `json:"link"`
+	Title       string      `json:"title"`
+	TopicPath   string      `json:"topic_path,omitempty"`
+	Topics      []string    `json:"topics,omitempty"`
+}
+
+// Dimension is an object representing a single dimension
+type Dimension struct {
+	Label string `json:"label"`
+	Name  string `json:"name"`
+}
+
+// Options holds the settings of an upload, each of which can be set by a command line flag
+type Options struct {
+	Alias                  string
+	BatchSize              int
+	DimensionsFilename     string
+	DryRun                 bool
+	Filename               string
+	Format                 string
+	Incremental            bool
+	MetadataIndex          string
+	ReportFilename         string
+	RetainIndices          int
+	TaxonomyFilename       string
+	TopicMode              string
+	TopicOverridesFilename string
+	Workers                int
+}
+
+// DefaultOptions returns the options of an upload run from the scripts directory
+func DefaultOptions() Options {
+	return Options{
+		Alias:              config.DefaultDatasetIndex,
+		BatchSize:          defaultBatchSize,
+		DimensionsFilename: defaultDimensionFile,
+		Filename:           defaultFilename,
+		MetadataIndex:      config.DefaultMetadataIndex,
+		RetainIndices:      defaultRetainIndices,
+		TaxonomyFilename:   defaultTaxonomyFile,
+		TopicMode:          topicModeLenient,
+		Workers:            defaultWorkers,
+	}
+}
+
+// Register adds the upload flags to the flag set, each flag defaults to the value
+// already set in the options
+func (o *Options) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Alias, "dataset-index", o.Alias, "the elasticsearch alias that datasets will be searched through, each upload creates a new index behind it")
+	fs.StringVar(&o.Filename, "filename", o.Filename, "the csv, json or ndjson filename that contains data to upload to elasticsearch")
+	fs.StringVar(&o.Format, "format", o.Format, "the format of the file, one of csv, json or ndjson, defaults to the format matching the file extension")
+	fs.StringVar(&o.DimensionsFilename, "dimensions-filename", o.DimensionsFilename, "the file locataion and name that contains a list of dataset dimensions")
+	fs.StringVar(&o.TaxonomyFilename, "taxonomy-filename", o.TaxonomyFilename, "the file locataion and name that contains the taxonomy hierarchy")
+	fs.IntVar(&o.BatchSize, "batch-size", o.BatchSize, "the number of documents sent to elasticsearch in each bulk request")
+	fs.IntVar(&o.Workers, "workers", o.Workers, "the number of bulk requests sent to elasticsearch at the same time")
+	fs.BoolVar(&o.Incremental, "incremental", o.Incremental, "upsert changed datasets and delete removed datasets in the index behind the alias, instead of loading a new index")
+	fs.IntVar(&o.RetainIndices, "retain-indices", o.RetainIndices, "the number of dataset indices to keep, including the new index, older indices are deleted")
+	fs.StringVar(&o.MetadataIndex, "metadata-index", o.MetadataIndex, "the elasticsearch index that the taxonomy and dimensions will be stored in, set to empty to skip")
+	fs.BoolVar(&o.DryRun, "dry-run", o.DryRun, "validate every dataset in the file and output a report, without writing to elasticsearch or the dimensions file")
+	fs.StringVar(&o.TopicMode, "topic-mode", o.TopicMode, "how datasets with a topic missing from the taxonomy are handled, strict fails the upload while lenient uploads them without topics")
+	fs.StringVar(&o.TopicOverridesFilename, "topic-overrides", o.TopicOverridesFilename, "a json file mapping topics missing from the taxonomy to topics in the taxonomy")
+	fs.StringVar(&o.ReportFilename, "report", o.ReportFilename, "the file the dry run report is written to, defaults to stdout")
+}
+
+// uploader holds the options of an upload along with the taxonomy that the topic of each
+// dataset is looked up in
+type uploader struct {
+	opts           Options
+	taxonomy       models.Taxonomy
+	topicPaths     map[string][]string
+	topicOverrides map[string]string
+}
+
+// Run validates the datasets in the file of the options and, unless it is a dry run,
+// uploads them to elasticsearch along with the taxonomy and dimensions. The client is
+// not used by a dry run
+func Run(ctx context.Context, esAPI *es.API, opts Options) error {
+	if opts.Alias == "" {
+		opts.Alias = config.DefaultDatasetIndex
+	}
+
+	if opts.Filename == "" {
+		opts.Filename = defaultFilename
+	}
+
+	format, err := detectFormat(opts.Format, opts.Filename)
+	if err != nil {
+		log.Event(ctx, "invalid input format", log.ERROR, log.Error(err))
+		return err
+	}
+	opts.Format = format
+
+	if opts.TopicMode == "" {
+		opts.TopicMode = topicModeLenient
+	}
+
+	if opts.TopicMode != topicModeLenient && opts.TopicMode != topicModeStrict {
+		err = errors.New("invalid topic mode, expected one of strict or lenient")
+		log.Event(ctx, "invalid topic mode", log.ERROR, log.Error(err), log.Data{"topic_mode": opts.TopicMode})
+		return err
+	}
+
+	if opts.BatchSize < 1 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	if opts.Workers < 1 {
+		opts.Workers = defaultWorkers
+	}
+
+	if opts.RetainIndices < 1 {
+		opts.RetainIndices = 1
+	}
+
+	if opts.DimensionsFilename == "" {
+		opts.DimensionsFilename = defaultDimensionFile
+	}
+
+	if opts.TaxonomyFilename == "" {
+		opts.TaxonomyFilename = defaultTaxonomyFile
+	}
+
+	log.Event(ctx, "upload options", log.INFO, log.Data{"dataset_index": opts.Alias, "filename": opts.Filename, "format": opts.Format, "dimensions-file": opts.DimensionsFilename, "taxonomy-file": opts.TaxonomyFilename, "metadata_index": opts.MetadataIndex, "batch_size": opts.BatchSize, "workers": opts.Workers, "retain_indices": opts.RetainIndices, "incremental": opts.Incremental, "dry_run": opts.DryRun, "topic_mode": opts.TopicMode, "topic_overrides": opts.TopicOverridesFilename})
+
+	u := &uploader{
+		opts:       opts,
+		topicPaths: make(map[string][]string),
+	}
+
+	// Read in Taxonomy into memory
+	taxonomyFile, err := ioutil.ReadFile(opts.TaxonomyFilename)
+	if err != nil {
+		log.Event(ctx, "failed to read taxonomy file", log.ERROR, log.Error(err), log.Data{"taxonomy_filename": opts.TaxonomyFilename})
+		return err
+	}
+
+	if err = json.Unmarshal([]byte(taxonomyFile), &u.taxonomy); err != nil {
+		log.Event(ctx, "unable to unmarshal taxonomy into struct", log.ERROR, log.Error(err), log.Data{"taxonomy_filename": opts.TaxonomyFilename})
+		return err
+	}
+
+	// Invert taxonomy so each topic has a list of parent topics and store in map
+	u.storeTopicPaths(u.taxonomy.Topics, nil)
+
+	if opts.TopicOverridesFilename != "" {
+		if u.topicOverrides, err = readTopicOverrides(opts.TopicOverridesFilename); err != nil {
+			log.Event(ctx, "failed to read topic overrides file", log.ERROR, log.Error(err), log.Data{"topic_overrides": opts.TopicOverridesFilename})
+			return err
+		}
+	}
+
+	records, err := readRecords(ctx, opts.Filename, opts.Format)
+	if err != nil {
+		log.Event(ctx, "failed to retrieve dataset docs", log.ERROR, log.Error(err))
+		return err
+	}
+
+	if opts.DryRun {
+		return u.validateDocs(ctx, records)
+	}
+
+	// read the dataset documents and the dimensions across them from the input file
+	docs, dimensions, err := u.readDocs(ctx, records)
+	if err != nil {
+		log.Event(ctx, "failed to retrieve dataset docs", log.ERROR, log.Error(err))
+		return err
+	}
+
+	if opts.Incremental {
+		// datasets in the index are deleted when their alias is missing from the file, so
+		// a skipped record must keep its dataset, and a record without an alias could be any
+		skipped, unidentified := skippedAliases(records, docs)
+		if unidentified > 0 {
+			err = fmt.Errorf("%d records could not be read or have no alias", unidentified)
+			log.Event(ctx, "refusing incremental upload, fix the file or upload to a new index", log.ERROR, log.Error(err), log.Data{"filename": opts.Filename})
+			return err
+		}
+
+		err = u.upsertDocs(ctx, esAPI, opts.Alias, docs, skipped)
+	} else {
+		err = u.reindexDocs(ctx, esAPI, opts.Alias, docs)
+	}
+	if err != nil {
+		return err
+	}
+
+	// store taxonomy and dimensions for the search API to load
+	if opts.MetadataIndex != "" {
+		if err = metadata.WriteIndex(ctx, esAPI, opts.MetadataIndex, u.taxonomy, dimensions); err != nil {
+			log.Event(ctx, "failed to store taxonomy and dimensions in metadata index", log.ERROR, log.Error(err))
+			return err
+		}
+	}
+
+	log.Event(ctx, "successfully loaded in dataset docs", log.INFO)
+
+	return nil
+}
+
+// reindexDocs loads the documents into a new index and, once every document has been
+// indexed, atomically points the alias at it so searches are never served from a partial load
+func (u *uploader) reindexDocs(ctx context.Context, esAPI *es.API, alias string, docs []rowDocument) error {
+	newIndex := alias + "-" + time.Now().UTC().Format(indexTimestampFormat)
+	logData := log.Data{"alias": alias, "new_index": newIndex}
+
+	// create elasticsearch index with settings/mapping
+	status, err := esAPI.CreateSearchIndex(ctx, newIndex, mappingsFile)
+	if err != nil {
+		logData["status"] = status
+		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	summary := indexDocuments(ctx, esAPI, newIndex, docs, u.opts.BatchSize, u.opts.Workers)
+
+	logData["indexed"] = summary.Indexed
+	logData["failed"] = summary.Failed
+
+	if summary.Failed > 0 {
+		logData["failures"] = summary.Failures
+		err = fmt.Errorf("%d documents failed to index", summary.Failed)
+		log.Event(ctx, "failed to index dataset docs, alias has not been changed", log.ERROR, log.Error(err), logData)
+		removeIndex(ctx, esAPI, newIndex)
+		return err
+	}
+
+	if err = verifyIndex(ctx, esAPI, newIndex, summary.Indexed); err != nil {
+		log.Event(ctx, "failed to verify new index, alias has not been changed", log.ERROR, log.Error(err), logData)
+		removeIndex(ctx, esAPI, newIndex)
+		return err
+	}
+
+	oldIndices, err := swapAlias(ctx, esAPI, alias, newIndex)
+	if err != nil {
+		log.Event(ctx, "failed to point alias at new index", log.ERROR, log.Error(err), logData)
+		removeIndex(ctx, esAPI, newIndex)
+		return err
+	}
+
+	logData["old_indices"] = oldIndices
+	log.Event(ctx, "alias now points at new index", log.INFO, logData)
+
+	if err = pruneIndices(ctx, esAPI, alias, u.opts.RetainIndices); err != nil {
+		log.Event(ctx, "failed to prune old indices", log.WARN, log.Error(err), logData)
+	}
+
+	return nil
+}
+
+// readRecords reads every dataset record from the input file
+func readRecords(ctx context.Context, filename, format string) ([]inputRecord, error) {
+	logData := log.Data{"filename": filename, "format": format}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Event(ctx, "failed to open the input file", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := readInput(file, format)
+	if err != nil {
+		log.Event(ctx, "failed to read datasets from input file", log.ERROR, log.Error(err), logData)
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// validateDocs writes a report of every issue found in the records, returning an error
+// if any dataset would be skipped by an upload
+func (u *uploader) validateDocs(ctx context.Context, records []inputRecord) error {
+	_, issues := u.validateRecords(records)
+	report := u.newValidationReport(records, issues)
+	reportFilename := u.opts.ReportFilename
+
+	out := os.Stdout
+	if reportFilename != "" {
+		file, err := os.Create(reportFilename)
+		if err != nil {
+			log.Event(ctx, "failed to create report file", log.ERROR, log.Error(err), log.Data{"report": reportFilename})
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := writeReport(out, report); err != nil {
+		log.Event(ctx, "failed to write report", log.ERROR, log.Error(err), log.Data{"report": reportFilename})
+		return err
+	}
+
+	logData := log.Data{"records": report.Records, "valid": report.Valid, "invalid": report.Invalid, "errors": report.Errors, "warnings": report.Warnings}
+	if report.Errors > 0 {
+		err := fmt.Errorf("%d datasets are invalid", report.Invalid)
+		log.Event(ctx, "dry run found invalid datasets", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	log.Event(ctx, "dry run found no invalid datasets", log.INFO, logData)
+
+	return nil
+}
+
+// readDocs creates a dataset document from each valid record, using the dataset alias as
+// the document id, and stores the dimensions across all datasets in the dimensions file
+func (u *uploader) readDocs(ctx context.Context, records []inputRecord) ([]rowDocument, models.DimensionsDoc, error) {
+	docs, issues := u.validateRecords(records)
+
+	for _, issue := range issues {
+		logData := log.Data{"row": issue.Row, "alias": issue.Alias, "field": issue.Field, "reason": issue.Message}
+		if issue.Severity == severityError {
+			log.Event(ctx, "invalid dataset, skipping", log.WARN, logData)
+		} else {
+			log.Event(ctx, "dataset has a problem, uploading anyway", log.WARN, logData)
+		}
+	}
+
+	if topics := u.unknownTopics(records); len(topics) > 0 {
+		logData := log.Data{"topics": topics, "topic_mode": u.opts.TopicMode}
+		if u.opts.TopicMode == topicModeStrict {
+			err := fmt.Errorf("%d topics not found in taxonomy", len(topics))
+			log.Event(ctx, "datasets have topics not found in taxonomy, add them to the topic overrides file", log.ERROR, log.Error(err), logData)
+			return nil, models.DimensionsDoc{}, err
+		}
+
+		log.Event(ctx, "datasets have topics not found in taxonomy, uploading them without topics", log.WARN, logData)
+	}
+
+	dimensionMap := make(map[string]string)
+	for _, doc := range docs {
+		for _, dimension := range doc.Dataset.Dimensions {
+			dimensionMap[dimension.Name] = dimension.Label
+		}
+	}
+
+	log.Event(ctx, "dimensions?", log.Data{"dimensions": dimensionMap})
+
+	dimensionList := createDimensionList(ctx, dimensionMap)
+	// Store dimensions to a file
+	dimensionsFile, err := json.MarshalIndent(dimensionList, "", "  ")
+	if err != nil {
+		log.Event(ctx, "failed to marshal dimensions with indentation", log.ERROR, log.Error(err))
+		return nil, models.DimensionsDoc{}, err
+	}
+
+	if err = ioutil.WriteFile(u.opts.DimensionsFilename, dimensionsFile, 0644); err != nil {
+		log.Event(ctx, "failed to write to file", log.ERROR, log.Error(err), log.Data{"dimensions_filename": u.opts.DimensionsFilename})
+		return nil, models.DimensionsDoc{}, err
+	}
+
+	return docs, dimensionList, nil
+}
+
+// verifyIndex checks every uploaded document can be counted in the index before it is used
+func verifyIndex(ctx context.Context, esAPI *es.API, indexName string, uploaded int) error {
+	if status, err := esAPI.RefreshIndex(ctx, indexName); err != nil {
+		log.Event(ctx, "failed to refresh index", log.ERROR, log.Error(err), log.Data{"index": indexName, "status": status})
+		return err
+	}
+
+	count, status, err := esAPI.CountDocuments(ctx, indexName)
+	if err != nil {
+		log.Event(ctx, "failed to count documents in index", log.ERROR, log.Error(err), log.Data{"index": indexName, "status": status})
+		return err
+	}
+
+	if uploaded == 0 || count != uploaded {
+		return fmt.Errorf("index %s contains %d documents, expected %d", indexName, count, uploaded)
+	}
+
+	return nil
+}
+
+// swapAlias atomically points the alias at the new index, returning the indices it
+// pointed to before. An index with the same name as the alias, created before uploads
+// used aliases, is deleted in the same request
+func swapAlias(ctx context.Context, esAPI *es.API, alias, newIndex string) ([]string, error) {
+	oldIndices, status, err := esAPI.GetAliasIndices(ctx, alias)
+	if err != nil {
+		log.Event(ctx, "failed to get indices for alias", log.ERROR, log.Error(err), log.Data{"alias": alias, "status": status})
+		return nil, err
+	}
+
+	var removeIndices []string
+	if len(oldIndices) == 0 {
+		exists, status, err := esAPI.IndexExists(ctx, alias)
+		if err != nil {
+			log.Event(ctx, "failed to check for index with the same name as alias", log.ERROR, log.Error(err), log.Data{"alias": alias, "status": status})
+			return nil, err
+		}
+
+		if exists {
+			removeIndices = []string{alias}
+		}
+	}
+
+	if status, err = esAPI.SwapAlias(ctx, alias, newIndex, oldIndices, removeIndices); err != nil {
+		log.Event(ctx, "failed to swap alias", log.ERROR, log.Error(err), log.Data{"alias": alias, "status": status})
+		return nil, err
+	}
+
+	return append(oldIndices, removeIndices...), nil
+}
+
+// pruneIndices deletes the oldest indices created for the alias, keeping the newest
+// retain indices and any index the alias still points to
+func pruneIndices(ctx context.Context, esAPI *es.API, alias string, retain int) error {
+	indices, status, err := esAPI.ListIndices(ctx, alias+"-*")
+	if err != nil {
+		log.Event(ctx, "failed to list indices", log.ERROR, log.Error(err), log.Data{"alias": alias, "status": status})
+		return err
+	}
+
+	current, status, err := esAPI.GetAliasIndices(ctx, alias)
+	if err != nil {
+		log.Event(ctx, "failed to get indices for alias", log.ERROR, log.Error(err), log.Data{"alias": alias, "status": status})
+		return err
+	}
+
+	inUse := make(map[string]bool)
+	for _, index := range current {
+		inUse[index] = true
+	}
+
+	// only consider indices created by this script, the timestamp suffix sorts oldest first
+	var uploads []string
+	for _, index := range indices {
+		suffix := strings.TrimPrefix(index, alias+"-")
+		if _, err := time.Parse(indexTimestampFormat, suffix); err == nil {
+			uploads = append(uploads, index)
+		}
+	}
+	sort.Strings(uploads)
+
+	for i := 0; i < len(uploads)-retain; i++ {
+		if inUse[uploads[i]] {
+			continue
+		}
+
+		status, err := esAPI.DeleteSearchIndex(ctx, uploads[i])
+		if err != nil {
+			log.Event(ctx, "failed to delete old index", log.ERROR, log.Error(err), log.Data{"index": uploads[i], "status": status})
+			return err
+		}
+
+		log.Event(ctx, "deleted old index", log.INFO, log.Data{"index": uploads[i]})
+	}
+
+	return nil
+}
+
+// removeIndex deletes an index that failed to load, so it is not left behind
+func removeIndex(ctx context.Context, esAPI *es.API, indexName string) {
+	if status, err := esAPI.DeleteSearchIndex(ctx, indexName); err != nil {
+		log.Event(ctx, "failed to delete index after failed upload", log.ERROR, log.Error(err), log.Data{"index": indexName, "status": status})
+	}
+}
+
+// storeTopicPaths walks the taxonomy to any depth, storing the path from the
+// top level down to each topic against the topic's filterable title
+func (u *uploader) storeTopicPaths(topics []models.Topic, parentPath []string) {
+	for _, topic := range topics {
+		path := make([]string, len(parentPath), len(parentPath)+1)
+		copy(path, parentPath)
+		path = append(path, topic.FormattedTitle)
+
+		u.topicPaths[topic.FormattedTitle] = path
+
+		u.storeTopicPaths(topic.ChildTopics, path)
+	}
+}
+
+// readTopicOverrides reads a json object mapping each topic missing from the taxonomy
+// to the topic in the taxonomy it should be stored under
+func readTopicOverrides(filename string) (map[string]string, error) {
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides map[string]string
+	if err = json.Unmarshal(file, &overrides); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+func createDimensionList(ctx context.Context, dimensionMap map[string]string) models.DimensionsDoc {
+	var dimensions []models.DimensionObject
+	for k, v := range dimensionMap {
+		dimensions = append(dimensions, models.DimensionObject{
+			Label: v,
+			Name:  k,
+		})
+	}
+
+	return models.DimensionsDoc{
+		Dimensions: dimensions,
+		TotalCount: len(dimensions),
+	}
+}
+
+func check(headerRow []string) (map[string]int, error) {
+	hasHeaders := make(map[string]bool)
+	for _, header := range datasetcsv.Headers {
+		hasHeaders[header] = false
+	}
+
+	if len(headerRow) < 1 {
+		return nil, errors.New("empty header row")
+	}
+
+	var indexHeader = make(map[string]int)
+	for i, header := range headerRow {
+		if _, ok := hasHeaders[header]; !ok {
+			return nil, errors.New("invalid header: " + header)
+		}
+
+		hasHeaders[header] = true
+		indexHeader[header] = i
+	}
+
+	var hasHeadersMissing bool
+	var missingHeaders string
+	for key, value := range hasHeaders {
+		if !value {
+			hasHeadersMissing = true
+			missingHeaders = missingHeaders + key + " "
+		}
+	}
+
+	if hasHeadersMissing {
+		return nil, errors.New("missing header in row: " + missingHeaders)
+	}
+
+	return indexHeader, nil
+}
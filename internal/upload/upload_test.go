@@ -0,0 +1,126 @@
+package upload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSwapAlias(t *testing.T) {
+	tests := []struct {
+		name        string
+		indices     []string
+		aliases     map[string][]string
+		wantOld     []string
+		wantRequest string
+		wantIndices []string
+	}{
+		{
+			name:        "the alias is moved from the old index",
+			indices:     []string{"datasets-20200101000000", "datasets-20200601000000"},
+			aliases:     map[string][]string{"datasets": {"datasets-20200101000000"}},
+			wantOld:     []string{"datasets-20200101000000"},
+			wantRequest: `{"actions":[{"remove":{"index":"datasets-20200101000000","alias":"datasets"}},{"add":{"index":"datasets-20200601000000","alias":"datasets"}}]}`,
+			wantIndices: []string{"datasets-20200101000000", "datasets-20200601000000"},
+		},
+		{
+			name:        "an index with the name of the alias is replaced in the same request",
+			indices:     []string{"datasets", "datasets-20200601000000"},
+			aliases:     map[string][]string{},
+			wantOld:     []string{"datasets"},
+			wantRequest: `{"actions":[{"remove_index":{"index":"datasets"}},{"add":{"index":"datasets-20200601000000","alias":"datasets"}}]}`,
+			wantIndices: []string{"datasets-20200601000000"},
+		},
+		{
+			name:        "the first upload creates the alias",
+			indices:     []string{"datasets-20200601000000"},
+			aliases:     map[string][]string{},
+			wantRequest: `{"actions":[{"add":{"index":"datasets-20200601000000","alias":"datasets"}}]}`,
+			wantIndices: []string{"datasets-20200601000000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeElasticsearch{indices: make(map[string]bool), aliases: tt.aliases}
+			for _, index := range tt.indices {
+				fake.indices[index] = true
+			}
+
+			esAPI, closeServer := newFakeAPI(t, fake)
+			defer closeServer()
+
+			old, err := swapAlias(context.Background(), esAPI, "datasets", "datasets-20200601000000")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(old, tt.wantOld) {
+				t.Errorf("old indices = %v, want %v", old, tt.wantOld)
+			}
+			if len(fake.aliasRequests) != 1 || fake.aliasRequests[0] != tt.wantRequest {
+				t.Errorf("alias requests = %v, want a single request %s", fake.aliasRequests, tt.wantRequest)
+			}
+			if got := fake.indexNames(); !reflect.DeepEqual(got, tt.wantIndices) {
+				t.Errorf("indices = %v, want %v", got, tt.wantIndices)
+			}
+			if got := fake.aliases["datasets"]; !reflect.DeepEqual(got, []string{"datasets-20200601000000"}) {
+				t.Errorf("alias points to %v, want the new index", got)
+			}
+		})
+	}
+}
+
+func TestPruneIndices(t *testing.T) {
+	tests := []struct {
+		name        string
+		retain      int
+		alias       []string
+		wantDeleted []string
+	}{
+		{
+			name:        "the oldest indices are deleted",
+			retain:      2,
+			alias:       []string{"datasets-20200401000000"},
+			wantDeleted: []string{"datasets-20200101000000", "datasets-20200201000000"},
+		},
+		{
+			name:        "an old index the alias points to is kept",
+			retain:      2,
+			alias:       []string{"datasets-20200101000000"},
+			wantDeleted: []string{"datasets-20200201000000"},
+		},
+		{
+			name:   "nothing is deleted when retaining every index",
+			retain: 4,
+			alias:  []string{"datasets-20200401000000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeElasticsearch{
+				indices: map[string]bool{
+					"datasets-20200101000000": true,
+					"datasets-20200201000000": true,
+					"datasets-20200301000000": true,
+					"datasets-20200401000000": true,
+					// indices not created by an upload are never pruned
+					"datasets-archive": true,
+				},
+				aliases: map[string][]string{"datasets": tt.alias},
+			}
+
+			esAPI, closeServer := newFakeAPI(t, fake)
+			defer closeServer()
+
+			if err := pruneIndices(context.Background(), esAPI, "datasets", tt.retain); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(fake.deleted, tt.wantDeleted) {
+				t.Errorf("deleted = %v, want %v", fake.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
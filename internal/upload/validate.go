@@ -0,0 +1,232 @@
+package upload
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	es "github.com/ONSdigital/dp-census-dataset-search-api/internal/elasticsearch"
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+// A list of issue severities, datasets with an error are not uploaded while datasets
+// with a warning are uploaded as they are
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// A list of the ways topics missing from the taxonomy are handled, in strict mode the
+// upload fails while in lenient mode the datasets are uploaded without topics
+const (
+	topicModeLenient = "lenient"
+	topicModeStrict  = "strict"
+)
+
+// validationIssue is a problem found with a single dataset in the input file
+type validationIssue struct {
+	Row      int    `json:"row"`
+	Alias    string `json:"alias,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// validationReport is the result of validating every dataset in the input file
+type validationReport struct {
+	Filename      string            `json:"filename"`
+	Format        string            `json:"format"`
+	Records       int               `json:"records"`
+	Valid         int               `json:"valid"`
+	Invalid       int               `json:"invalid"`
+	Errors        int               `json:"errors"`
+	Warnings      int               `json:"warnings"`
+	UnknownTopics map[string]int    `json:"unknown_topics,omitempty"`
+	Issues        []validationIssue `json:"issues"`
+}
+
+func newIssue(row int, alias, field, severity, message string) validationIssue {
+	return validationIssue{
+		Row:      row,
+		Alias:    alias,
+		Field:    field,
+		Severity: severity,
+		Message:  message,
+	}
+}
+
+// validateRecords checks every record, returning a document for each record without
+// errors along with every issue found across all records
+func (u *uploader) validateRecords(records []inputRecord) ([]rowDocument, []validationIssue) {
+	var docs []rowDocument
+	var issues []validationIssue
+
+	aliases := make(map[string]int)
+
+	for _, record := range records {
+		recordIssues := record.Issues
+
+		// a record that could not be read has no dataset worth checking
+		var dataset *Dataset
+		if len(recordIssues) == 0 {
+			var datasetIssues []validationIssue
+			dataset, datasetIssues = u.validateInput(record.Row, record.Input)
+			recordIssues = append(recordIssues, datasetIssues...)
+		}
+
+		if alias := record.Input.Alias; alias != "" {
+			if row, ok := aliases[alias]; ok {
+				recordIssues = append(recordIssues, newIssue(record.Row, alias, "alias", severityError, fmt.Sprintf("alias already used by row %d", row)))
+			} else {
+				aliases[alias] = record.Row
+			}
+		}
+
+		issues = append(issues, recordIssues...)
+
+		if hasErrors(recordIssues) {
+			continue
+		}
+
+		docs = append(docs, rowDocument{
+			Row:     record.Row,
+			Action:  es.BulkIndex,
+			ID:      dataset.Alias,
+			Dataset: dataset,
+		})
+	}
+
+	return docs, issues
+}
+
+// validateInput checks a dataset read from any format, the returned dataset has its
+// topics set to the path of the topic in the taxonomy
+func (u *uploader) validateInput(row int, input datasetInput) (*Dataset, []validationIssue) {
+	dataset := input.Dataset
+
+	var issues []validationIssue
+
+	if dataset.Alias == "" {
+		issues = append(issues, newIssue(row, "", "alias", severityError, "dataset has no alias to use as its id"))
+	}
+
+	for i, dimension := range dataset.Dimensions {
+		if dimension.Name == "" || dimension.Label == "" {
+			issues = append(issues, newIssue(row, dataset.Alias, "dimensions", severityError, fmt.Sprintf("dimension %d is missing a name or label", i+1)))
+		}
+	}
+
+	if dataset.Link != "" {
+		if link, err := url.ParseRequestURI(dataset.Link); err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+			issues = append(issues, newIssue(row, dataset.Alias, "link", severityWarning, "link is not a valid http or https url: "+dataset.Link))
+		}
+	}
+
+	dataset.Topics = nil
+	dataset.TopicPath = ""
+
+	topic, path, ok := u.resolveTopic(input)
+	if !ok {
+		severity := severityWarning
+		if u.opts.TopicMode == topicModeStrict {
+			severity = severityError
+		}
+
+		issues = append(issues, newIssue(row, dataset.Alias, "topic", severity, "topic not found in taxonomy: "+topic))
+	}
+
+	if len(path) > 0 {
+		dataset.Topics = path
+		dataset.TopicPath = strings.Join(path, models.TopicPathSeparator)
+	}
+
+	return &dataset, issues
+}
+
+// resolveTopic returns the topic of the dataset, after applying any override, along with
+// its path in the taxonomy. A dataset without a topic resolves to an empty path
+func (u *uploader) resolveTopic(input datasetInput) (string, []string, bool) {
+	topic := input.Topic
+	if topic == "" && len(input.Topics) > 0 {
+		topic = input.Topics[len(input.Topics)-1]
+	}
+
+	if topic == "" {
+		return "", nil, true
+	}
+
+	if override, ok := u.topicOverrides[topic]; ok {
+		topic = override
+	}
+
+	// find topic hierarchy - using taxonomy map
+	path, ok := u.topicPaths[topic]
+
+	return topic, path, ok
+}
+
+// unknownTopics counts the datasets for each topic that could not be found in the taxonomy
+func (u *uploader) unknownTopics(records []inputRecord) map[string]int {
+	topics := make(map[string]int)
+	for _, record := range records {
+		if len(record.Issues) > 0 {
+			continue
+		}
+
+		if topic, _, ok := u.resolveTopic(record.Input); !ok {
+			topics[topic]++
+		}
+	}
+
+	return topics
+}
+
+func hasErrors(issues []validationIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == severityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newValidationReport summarises the issues found across the records
+func (u *uploader) newValidationReport(records []inputRecord, issues []validationIssue) validationReport {
+	report := validationReport{
+		Filename: u.opts.Filename,
+		Format:   u.opts.Format,
+		Records:  len(records),
+		Issues:   []validationIssue{},
+	}
+
+	if topics := u.unknownTopics(records); len(topics) > 0 {
+		report.UnknownTopics = topics
+	}
+
+	invalidRows := make(map[int]bool)
+	for _, issue := range issues {
+		if issue.Severity == severityError {
+			report.Errors++
+			invalidRows[issue.Row] = true
+		} else {
+			report.Warnings++
+		}
+
+		report.Issues = append(report.Issues, issue)
+	}
+
+	report.Invalid = len(invalidRows)
+	report.Valid = report.Records - report.Invalid
+
+	return report
+}
+
+func writeReport(w io.Writer, report validationReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
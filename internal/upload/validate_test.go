@@ -0,0 +1,150 @@
+package upload
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-dataset-search-api/models"
+)
+
+var testTaxonomy = models.Taxonomy{
+	Topics: []models.Topic{
+		{
+			Title:          "Economy",
+			FormattedTitle: "economy",
+			ChildTopics: []models.Topic{
+				{Title: "Inflation and price indices", FormattedTitle: "inflationandpriceindices"},
+			},
+		},
+	},
+}
+
+// newTestUploader creates an uploader for the test taxonomy with the topic mode and overrides
+func newTestUploader(topicMode string, overrides map[string]string) *uploader {
+	u := &uploader{
+		opts:           Options{TopicMode: topicMode},
+		taxonomy:       testTaxonomy,
+		topicPaths:     make(map[string][]string),
+		topicOverrides: overrides,
+	}
+	u.storeTopicPaths(testTaxonomy.Topics, nil)
+
+	return u
+}
+
+func TestValidateTopics(t *testing.T) {
+	records := []inputRecord{
+		{Row: 2, Input: datasetInput{Dataset: Dataset{Alias: "a"}, Topic: "inflationandpriceindices"}},
+		{Row: 3, Input: datasetInput{Dataset: Dataset{Alias: "b"}, Topic: "prices"}},
+		{Row: 4, Input: datasetInput{Dataset: Dataset{Alias: "c"}, Topic: "unknown"}},
+		{Row: 5, Input: datasetInput{Dataset: Dataset{Alias: "d"}}},
+	}
+	overrides := map[string]string{"prices": "inflationandpriceindices"}
+
+	tests := []struct {
+		name      string
+		topicMode string
+		wantIDs   []string
+		wantPaths []string
+		wantIssue string
+	}{
+		{
+			name:      "lenient mode uploads datasets with an unknown topic without topics",
+			topicMode: topicModeLenient,
+			wantIDs:   []string{"a", "b", "c", "d"},
+			wantPaths: []string{"economy/inflationandpriceindices", "economy/inflationandpriceindices", "", ""},
+			wantIssue: severityWarning,
+		},
+		{
+			name:      "strict mode skips datasets with an unknown topic",
+			topicMode: topicModeStrict,
+			wantIDs:   []string{"a", "b", "d"},
+			wantPaths: []string{"economy/inflationandpriceindices", "economy/inflationandpriceindices", ""},
+			wantIssue: severityError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newTestUploader(tt.topicMode, overrides)
+			docs, issues := u.validateRecords(records)
+
+			var ids, paths []string
+			for _, doc := range docs {
+				ids = append(ids, doc.ID)
+				paths = append(paths, doc.Dataset.TopicPath)
+			}
+
+			if !reflect.DeepEqual(ids, tt.wantIDs) || !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("ids, paths = %v, %v, want %v, %v", ids, paths, tt.wantIDs, tt.wantPaths)
+			}
+
+			want := []validationIssue{newIssue(4, "c", "topic", tt.wantIssue, "topic not found in taxonomy: unknown")}
+			if !reflect.DeepEqual(issues, want) {
+				t.Errorf("issues = %+v, want %+v", issues, want)
+			}
+
+			if got := u.unknownTopics(records); !reflect.DeepEqual(got, map[string]int{"unknown": 1}) {
+				t.Errorf("unknown topics = %v, want the unknown topic counted", got)
+			}
+		})
+	}
+}
+
+func TestReadDocsTopicMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the second record could not be read, so only the first has an unknown topic
+	records := []inputRecord{
+		{Row: 2, Input: datasetInput{Dataset: Dataset{Alias: "a"}, Topic: "unknown"}},
+		{Row: 3, Input: datasetInput{Topic: "missing"}, Issues: []validationIssue{newIssue(3, "", "", severityError, "row has 2 columns, expected 7")}},
+	}
+
+	for _, topicMode := range []string{topicModeLenient, topicModeStrict} {
+		u := newTestUploader(topicMode, nil)
+		u.opts.DimensionsFilename = filepath.Join(dir, "dimensions.json")
+
+		docs, _, err := u.readDocs(context.Background(), records)
+
+		if topicMode == topicModeStrict {
+			if err == nil || err.Error() != "1 topics not found in taxonomy" {
+				t.Errorf("strict err = %v, want the unknown topics counted", err)
+			}
+			continue
+		}
+
+		if err != nil || len(docs) != 1 || docs[0].Dataset.Topics != nil {
+			t.Errorf("lenient docs, err = %+v, %v, want the dataset uploaded without topics", docs, err)
+		}
+	}
+}
+
+func TestReadTopicOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "overrides.json")
+	if err = ioutil.WriteFile(filename, []byte(`{"prices":"inflationandpriceindices"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	overrides, err := readTopicOverrides(filename)
+	if err != nil || !reflect.DeepEqual(overrides, map[string]string{"prices": "inflationandpriceindices"}) {
+		t.Errorf("overrides, err = %v, %v", overrides, err)
+	}
+
+	if _, err = readTopicOverrides(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error reading a missing overrides file")
+	}
+}
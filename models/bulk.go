@@ -0,0 +1,23 @@
+package models
+
+// BulkResponse represents the response from the elasticsearch bulk api, items
+// are in the same order as the documents in the request
+type BulkResponse struct {
+	Took   int                   `json:"took"`
+	Errors bool                  `json:"errors"`
+	Items  []map[string]BulkItem `json:"items"`
+}
+
+// BulkItem represents the result of a single action within a bulk request
+type BulkItem struct {
+	Index  string         `json:"_index"`
+	ID     string         `json:"_id"`
+	Status int            `json:"status"`
+	Error  *BulkItemError `json:"error,omitempty"`
+}
+
+// BulkItemError represents the reason a single action within a bulk request failed
+type BulkItemError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
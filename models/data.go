@@ -1,7 +1,19 @@
 package models
 
 type SearchResponse struct {
-	Hits Hits `json:"hits"`
+	Aggregations map[string]AggregationResult `json:"aggregations,omitempty"`
+	Hits         Hits                         `json:"hits"`
+}
+
+// AggregationResult represents the buckets returned for a single named aggregation
+type AggregationResult struct {
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Bucket represents a unique value of an aggregated field and the number of documents containing it
+type Bucket struct {
+	Key      string `json:"key"`
+	DocCount int    `json:"doc_count"`
 }
 
 type Hits struct {
@@ -38,9 +50,8 @@ type SearchResult struct {
 	Description string      `json:"description,omitempty"`
 	Dimensions  []Dimension `json:"dimensions,omitempty"`
 	Title       string      `json:"title,omitempty"`
-	Topic1      string      `json:"topic1,omitempty"`
-	Topic2      string      `json:"topic2,omitempty"`
-	Topic3      string      `json:"topic3,omitempty"`
+	TopicPath   string      `json:"topic_path,omitempty"`
+	Topics      []string    `json:"topics,omitempty"`
 	Link        string      `json:"link,omitempty"`
 	Matches     Matches     `json:"matches,omitempty"`
 }
@@ -58,7 +69,5 @@ type Matches struct {
 	DimensionLabel []string `json:"dimensions.label,omitempty"`
 	DimensionName  []string `json:"dimensions.name,omitempty"`
 	Title          []string `json:"title,omitempty"`
-	Topic1         []string `json:"topic1,omitempty"`
-	Topic2         []string `json:"topic2,omitempty"`
-	Topic3         []string `json:"topic3,omitempty"`
+	Topics         []string `json:"topics,omitempty"`
 }
@@ -1,5 +1,7 @@
 package models
 
+import "errors"
+
 // DimensionsDoc represents a list of dimensions
 type DimensionsDoc struct {
 	Dimensions []DimensionObject `json:"items"`
@@ -11,3 +13,20 @@ type DimensionObject struct {
 	Label string `json:"label,omitempty"`
 	Name  string `json:"name,omitempty"`
 }
+
+// Validate checks every dimension has a unique name
+func (d DimensionsDoc) Validate() error {
+	names := make(map[string]bool)
+	for _, dimension := range d.Dimensions {
+		if dimension.Name == "" {
+			return errors.New("dimensions contain a dimension missing a name")
+		}
+
+		if names[dimension.Name] {
+			return errors.New("dimensions contain duplicate dimension: " + dimension.Name)
+		}
+		names[dimension.Name] = true
+	}
+
+	return nil
+}
@@ -10,9 +10,13 @@ import (
 const (
 	maximumDimensionFilters, maximumTopicFilters = 10, 10
 	dimensionName                                = "dimensions.name"
-	topic1                                       = "topic1"
-	topic2                                       = "topic2"
-	topic3                                       = "topic3"
+
+	// TopicsField is the document field storing every topic on the path to the dataset's topic
+	TopicsField = "topics"
+
+	// TopicPathHierarchyField indexes every leading part of a dataset's topic path, so a
+	// term query against it matches datasets stored against a topic or its descendants
+	TopicPathHierarchyField = "topic_path.hierarchy"
 )
 
 // ErrorInvalidTopics - return error
@@ -53,8 +57,12 @@ func ValidateDimensions(dimensions string) ([]Filter, error) {
 	return filters, nil
 }
 
-// ValidateTopics checks the values in topics are valid
-func ValidateTopics(topics string) ([]Filter, error) {
+// ValidateTopics checks the values in topics exist in the taxonomy, a topic can be at
+// any level and matches datasets stored against it or its descendants. Topics at the
+// same level of the taxonomy are combined with OR and each level is combined with AND,
+// e.g. economy,people,inflationandpriceindices matches datasets under economy or people
+// and also under inflationandpriceindices
+func ValidateTopics(topics string, taxonomy Taxonomy) ([]Filter, error) {
 	if topics == "" {
 		return nil, nil
 	}
@@ -65,17 +73,21 @@ func ValidateTopics(topics string) ([]Filter, error) {
 		return nil, errs.ErrTooManyTopicFilters
 	}
 
-	var invalidTopics, topic1List, topic2List, topic3List []string
+	var invalidTopics []string
+	var levels [][]string
 	for _, topic := range topicList {
-		if validTopics[topic] < 1 {
+		path, ok := taxonomy.Path(topic)
+		if !ok {
 			invalidTopics = append(invalidTopics, topic)
-		} else if validTopics[topic] == 1 {
-			topic1List = append(topic1List, topic)
-		} else if validTopics[topic] == 2 {
-			topic2List = append(topic2List, topic)
-		} else if validTopics[topic] == 3 {
-			topic3List = append(topic3List, topic)
+			continue
 		}
+
+		for len(levels) < len(path) {
+			levels = append(levels, nil)
+		}
+
+		level := len(path) - 1
+		levels[level] = append(levels[level], strings.Join(path, TopicPathSeparator))
 	}
 
 	if len(invalidTopics) > 0 {
@@ -83,113 +95,13 @@ func ValidateTopics(topics string) ([]Filter, error) {
 	}
 
 	var filters []Filter
-	if len(topic1List) > 0 {
-		filters = append(filters, Filter{
-			Terms: map[string]interface{}{topic1: topic1List},
-		})
-	}
-
-	if len(topic2List) > 0 {
-		filters = append(filters, Filter{
-			Terms: map[string]interface{}{topic2: topic2List},
-		})
-	}
-
-	if len(topic3List) > 0 {
-		filters = append(filters, Filter{
-			Terms: map[string]interface{}{topic3: topic3List},
-		})
+	for _, paths := range levels {
+		if len(paths) > 0 {
+			filters = append(filters, Filter{
+				Terms: map[string]interface{}{TopicPathHierarchyField: paths},
+			})
+		}
 	}
 
 	return filters, nil
 }
-
-var validTopics = map[string]int{
-	"adoption":                                 3,
-	"ageing":                                   3,
-	"balanceofpayments":                        3,
-	"birthsdeathsandmarriages":                 2,
-	"causesofdeath":                            3,
-	"childhealth":                              3,
-	"conceptionandfertilityrates":              3,
-	"conditionsanddiseases":                    3,
-	"crimeandjustice":                          2,
-	"culturalidentity":                         2,
-	"deaths":                                   3,
-	"debt":                                     3,
-	"disability":                               3,
-	"divorce":                                  3,
-	"drugusealcoholandsmoking":                 3,
-	"earningsandworkinghours":                  3,
-	"economicinactivity":                       3,
-	"economicoutputandproductivity":            2,
-	"economy":                                  1,
-	"educationandchildcare":                    2,
-	"elections":                                2,
-	"electoralregistration":                    3,
-	"employmentandemployeetypes":               3,
-	"employmentandlabourmarket":                1,
-	"environmentalaccounts":                    2,
-	"ethnicity":                                3,
-	"expenditure":                              3,
-	"families":                                 3,
-	"generalelections":                         3,
-	"governmentpublicsectorandtaxes":           2,
-	"grossdisposablehouseholdincome":           3,
-	"grossdomesticproductgdp":                  2,
-	"grossvalueaddedgva":                       2,
-	"healthandlifeexpectancies":                3,
-	"healthandsocialcare":                      2,
-	"healthandwellbeing":                       3,
-	"healthcaresystem":                         3,
-	"healthinequalities":                       3,
-	"homeinternetandsocialmediausage":          3,
-	"householdcharacteristics":                 2,
-	"housing":                                  2,
-	"incomeandwealth":                          3,
-	"inflationandpriceindices":                 2,
-	"internationalmigration":                   3,
-	"investmentspensionsandtrusts":             2,
-	"labourproductivity":                       3,
-	"language":                                 3,
-	"leisureandtourism":                        2,
-	"lifeexpectancies":                         3,
-	"livebirths":                               3,
-	"localgovernmentelections":                 3,
-	"localgovernmentfinance":                   3,
-	"marriagecohabitationandcivilpartnerships": 3,
-	"maternities":                              3,
-	"mentalhealth":                             3,
-	"migrationwithintheuk":                     3,
-	"nationalaccounts":                         2,
-	"outofworkbenefits":                        3,
-	"output":                                   3,
-	"pensionssavingsandinvestments":            3,
-	"peopleinwork":                             2,
-	"peoplenotinwork":                          2,
-	"peoplepopulationandcommunity":             1,
-	"personalandhouseholdfinances":             2,
-	"populationandmigration":                   2,
-	"populationestimates":                      3,
-	"populationprojections":                    3,
-	"productivitymeasures":                     3,
-	"publicsectorfinance":                      3,
-	"publicsectorpersonnel":                    3,
-	"publicservicesproductivity":               3,
-	"publicspending":                           3,
-	"redundancies":                             3,
-	"regionalaccounts":                         2,
-	"religion":                                 3,
-	"researchanddevelopmentexpenditure":        3,
-	"satelliteaccounts":                        3,
-	"sexuality":                                3,
-	"socialcare":                               3,
-	"stillbirths":                              3,
-	"supplyandusetables":                       3,
-	"taxesandrevenue":                          3,
-	"uksectoraccounts":                         3,
-	"unemployment":                             3,
-	"wellbeing":                                2,
-	"workplacedisputesandworkingconditions":    3,
-	"workplacepensions":                        3,
-}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-dataset-search-api/apierrors"
+)
+
+var testTaxonomy = Taxonomy{
+	Topics: []Topic{
+		{
+			Title:          "Economy",
+			FormattedTitle: "economy",
+			ChildTopics: []Topic{
+				{
+					Title:          "Inflation and price indices",
+					FormattedTitle: "inflationandpriceindices",
+					ChildTopics: []Topic{
+						{Title: "Consumer prices", FormattedTitle: "consumerprices"},
+					},
+				},
+				{Title: "Gross domestic product", FormattedTitle: "grossdomesticproduct"},
+			},
+		},
+		{Title: "People", FormattedTitle: "people"},
+	},
+}
+
+func TestValidateTopics(t *testing.T) {
+	tests := []struct {
+		name    string
+		topics  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "no topics",
+			topics: "",
+			want:   `null`,
+		},
+		{
+			name:   "a single topic matches its descendants through the path",
+			topics: "inflationandpriceindices",
+			want:   `[{"terms":{"topic_path.hierarchy":["economy/inflationandpriceindices"]}}]`,
+		},
+		{
+			name:   "topics at the same level are combined in one filter",
+			topics: "economy,people",
+			want:   `[{"terms":{"topic_path.hierarchy":["economy","people"]}}]`,
+		},
+		{
+			name:   "topics at different levels are separate filters, ordered by level",
+			topics: "consumerprices,grossdomesticproduct,people,inflationandpriceindices",
+			want: `[{"terms":{"topic_path.hierarchy":["people"]}},` +
+				`{"terms":{"topic_path.hierarchy":["economy/grossdomesticproduct","economy/inflationandpriceindices"]}},` +
+				`{"terms":{"topic_path.hierarchy":["economy/inflationandpriceindices/consumerprices"]}}]`,
+		},
+		{
+			name:    "unknown topics",
+			topics:  "economy,unknown,missing",
+			wantErr: "invalid list of topics to filter by: unknown,missing",
+		},
+		{
+			name:    "too many topics",
+			topics:  "a,b,c,d,e,f,g,h,i,j,k",
+			wantErr: errs.ErrTooManyTopicFilters.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := ValidateTopics(tt.topics, testTaxonomy)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			b, err := json.Marshal(filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(b) != tt.want {
+				t.Errorf("filters = %s, want %s", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaxonomyPath(t *testing.T) {
+	tests := []struct {
+		topic  string
+		want   string
+		wantOK bool
+	}{
+		{topic: "economy", want: `["economy"]`, wantOK: true},
+		{topic: "consumerprices", want: `["economy","inflationandpriceindices","consumerprices"]`, wantOK: true},
+		{topic: "people", want: `["people"]`, wantOK: true},
+		{topic: "unknown", want: `null`},
+	}
+
+	for _, tt := range tests {
+		path, ok := testTaxonomy.Path(tt.topic)
+
+		b, err := json.Marshal(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != tt.want || ok != tt.wantOK {
+			t.Errorf("Path(%s) = %s, %t, want %s, %t", tt.topic, b, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// A list of health statuses, ordered from healthy to unhealthy
+const (
+	HealthOK       = "OK"
+	HealthWarning  = "WARNING"
+	HealthCritical = "CRITICAL"
+)
+
+// Health represents the result of the latest check of the service and its dependencies
+type Health struct {
+	Status        string              `json:"status"`
+	CheckedAt     time.Time           `json:"checked_at"`
+	Elasticsearch ElasticsearchHealth `json:"elasticsearch"`
+	DatasetIndex  IndexHealth         `json:"dataset_index"`
+	Taxonomy      MetadataHealth      `json:"taxonomy"`
+	Dimensions    MetadataHealth      `json:"dimensions"`
+	ShuttingDown  bool                `json:"shutting_down,omitempty"`
+}
+
+// ElasticsearchHealth represents the health of the elasticsearch cluster
+type ElasticsearchHealth struct {
+	Status        string `json:"status"`
+	ClusterName   string `json:"cluster_name,omitempty"`
+	ClusterStatus string `json:"cluster_status,omitempty"`
+	NumberOfNodes int    `json:"number_of_nodes,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// IndexHealth represents whether an index exists and the number of documents it contains
+type IndexHealth struct {
+	Status        string `json:"status"`
+	Name          string `json:"name"`
+	Exists        bool   `json:"exists"`
+	DocumentCount int    `json:"document_count"`
+	Error         string `json:"error,omitempty"`
+}
+
+// MetadataHealth represents when a metadata document (taxonomy or dimensions) was last loaded
+type MetadataHealth struct {
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// ClusterHealth represents the response from the elasticsearch cluster health api
+type ClusterHealth struct {
+	ClusterName   string `json:"cluster_name"`
+	Status        string `json:"status"`
+	NumberOfNodes int    `json:"number_of_nodes"`
+}
+
+// CountResponse represents the response from the elasticsearch count api
+type CountResponse struct {
+	Count int `json:"count"`
+}
@@ -2,12 +2,24 @@ package models
 
 // Body represents the request body to elasticsearch
 type Body struct {
-	From      int        `json:"from"`
-	Size      int        `json:"size"`
-	Highlight *Highlight `json:"highlight,omitempty"`
-	Query     Query      `json:"query"`
-	Sort      []Scores   `json:"sort"`
-	TotalHits bool       `json:"track_total_hits"`
+	Aggregations map[string]Aggregation `json:"aggs,omitempty"`
+	From         int                    `json:"from"`
+	Size         int                    `json:"size"`
+	Highlight    *Highlight             `json:"highlight,omitempty"`
+	Query        Query                  `json:"query"`
+	Sort         []Scores               `json:"sort"`
+	TotalHits    bool                   `json:"track_total_hits"`
+}
+
+// Aggregation represents a single named aggregation to be calculated across matching documents
+type Aggregation struct {
+	Terms *TermsAggregation `json:"terms,omitempty"`
+}
+
+// TermsAggregation represents a bucket aggregation on the unique values of a field
+type TermsAggregation struct {
+	Field string `json:"field"`
+	Size  int    `json:"size,omitempty"`
 }
 
 // Highlight represents parts of the fields that matched
@@ -23,7 +35,8 @@ type Object struct{}
 
 // Query represents the request query details
 type Query struct {
-	Bool *Bool `json:"bool,omitempty"`
+	Bool     *Bool   `json:"bool,omitempty"`
+	MatchAll *Object `json:"match_all,omitempty"`
 }
 
 // Bool represents the desirable goals for query
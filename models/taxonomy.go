@@ -1,5 +1,7 @@
 package models
 
+import "errors"
+
 // Taxonomy represents the hierarchy of topics
 type Taxonomy struct {
 	Topics []Topic `json:"topics"`
@@ -9,5 +11,62 @@ type Taxonomy struct {
 type Topic struct {
 	Title          string  `json:"title"`
 	FormattedTitle string  `json:"filterable_title"`
+	DatasetCount   *int    `json:"dataset_count,omitempty"`
 	ChildTopics    []Topic `json:"child_topics,omitempty"`
 }
+
+// TopicPathSeparator separates each topic in the path from the top level of the taxonomy down to a topic
+const TopicPathSeparator = "/"
+
+// Path returns the list of filterable titles from the top level of the taxonomy
+// down to and including the topic, the topic can be at any depth in the hierarchy
+func (t Taxonomy) Path(topic string) ([]string, bool) {
+	return findPath(t.Topics, topic, nil)
+}
+
+func findPath(topics []Topic, topic string, path []string) ([]string, bool) {
+	for _, t := range topics {
+		topicPath := make([]string, len(path), len(path)+1)
+		copy(topicPath, path)
+		topicPath = append(topicPath, t.FormattedTitle)
+
+		if t.FormattedTitle == topic {
+			return topicPath, true
+		}
+
+		if result, ok := findPath(t.ChildTopics, topic, topicPath); ok {
+			return result, true
+		}
+	}
+
+	return nil, false
+}
+
+// Validate checks the taxonomy contains topics and that every topic has a
+// title and a filterable title which is unique across the whole taxonomy
+func (t Taxonomy) Validate() error {
+	if len(t.Topics) < 1 {
+		return errors.New("taxonomy contains no topics")
+	}
+
+	return validateTopics(t.Topics, make(map[string]bool))
+}
+
+func validateTopics(topics []Topic, filterableTitles map[string]bool) error {
+	for _, topic := range topics {
+		if topic.Title == "" || topic.FormattedTitle == "" {
+			return errors.New("taxonomy contains a topic missing a title or filterable title")
+		}
+
+		if filterableTitles[topic.FormattedTitle] {
+			return errors.New("taxonomy contains duplicate topic: " + topic.FormattedTitle)
+		}
+		filterableTitles[topic.FormattedTitle] = true
+
+		if err := validateTopics(topic.ChildTopics, filterableTitles); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}